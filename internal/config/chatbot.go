@@ -0,0 +1,13 @@
+package config
+
+// ChatbotConfig acota cuánto historial de una sesión se reenvía al modelo en cada mensaje
+type ChatbotConfig struct {
+	// HistoryTokenBudget son los tokens estimados de turnos anteriores que se reenvían; los más antiguos se descartan
+	HistoryTokenBudget int
+}
+
+func LoadChatbotConfig() ChatbotConfig {
+	return ChatbotConfig{
+		HistoryTokenBudget: getEnvInt("CHATBOT_HISTORY_TOKENS", 3000),
+	}
+}
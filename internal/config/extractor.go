@@ -0,0 +1,23 @@
+package config
+
+const (
+	ExtractorBackendAWS   = "aws"
+	ExtractorBackendLocal = "local"
+)
+
+// ExtractorConfig elige el backend que convierte documentos en líneas de producto.
+// EXTRACTOR_BACKEND=local permite procesar solicitudes sin acceso a AWS: los documentos se guardan
+// y leen en DocumentsDir en vez de S3, y sin LLM_PROVIDER no se arma el cliente de Bedrock.
+type ExtractorConfig struct {
+	Backend        string
+	TextractRegion string
+	DocumentsDir   string
+}
+
+func LoadExtractorConfig(textractRegion string) ExtractorConfig {
+	return ExtractorConfig{
+		Backend:        getEnv("EXTRACTOR_BACKEND", ExtractorBackendAWS),
+		TextractRegion: getEnv("TEXTRACT_REGION", textractRegion),
+		DocumentsDir:   getEnv("EXTRACTOR_LOCAL_DIR", "data/documents"),
+	}
+}
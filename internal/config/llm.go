@@ -0,0 +1,75 @@
+package config
+
+import (
+	"log"
+	"strconv"
+)
+
+const (
+	LLMProviderBedrock = "bedrock"
+	LLMProviderOpenAI  = "openai"
+	// LLMProviderNone no arma cliente: las llamadas al modelo fallan. Es el default con el extractor local.
+	LLMProviderNone = "none"
+)
+
+// LLMConfig elige el proveedor del modelo de lenguaje y sus parámetros por defecto.
+// LLM_PROVIDER=openai usa un servidor compatible con la API de OpenAI (p. ej. un modelo local) en LLM_BASE_URL.
+type LLMConfig struct {
+	Provider    string
+	Model       string
+	Region      string
+	MaxTokens   int
+	Temperature float64
+	BaseURL     string
+	APIKey      string
+	// RepairAttempts es cuántas veces se le reenvían al modelo los errores de validación de su salida
+	RepairAttempts int
+}
+
+func LoadLLMConfig() LLMConfig {
+	return LLMConfig{
+		Provider:    getEnv("LLM_PROVIDER", defaultLLMProvider()),
+		Model:       getEnv("LLM_MODEL", ""),
+		Region:      getEnv("LLM_REGION", "us-east-1"),
+		MaxTokens:   getEnvInt("LLM_MAX_TOKENS", 712),
+		Temperature: getEnvFloat("LLM_TEMPERATURE", 0),
+		BaseURL:     getEnv("LLM_BASE_URL", "http://localhost:11434/v1"),
+		APIKey:      getEnv("LLM_API_KEY", ""),
+
+		RepairAttempts: getEnvInt("LLM_REPAIR_ATTEMPTS", 2),
+	}
+}
+
+// defaultLLMProvider evita depender de Bedrock cuando la extracción corre sin AWS
+func defaultLLMProvider() string {
+	if getEnv("EXTRACTOR_BACKEND", ExtractorBackendAWS) == ExtractorBackendLocal {
+		return LLMProviderNone
+	}
+	return LLMProviderBedrock
+}
+
+func getEnvInt(k string, def int) int {
+	v := getEnv(k, "")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("valor inválido para %s: %q, se usa %d", k, v, def)
+		return def
+	}
+	return n
+}
+
+func getEnvFloat(k string, def float64) float64 {
+	v := getEnv(k, "")
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("valor inválido para %s: %q, se usa %v", k, v, def)
+		return def
+	}
+	return f
+}
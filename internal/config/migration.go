@@ -12,23 +12,32 @@ import (
 
 // RunMigrations corre todas las migraciones pendientes
 func RunMigrations(cfg DBConfig) {
+	runMigrations("file://internal/db/migrations", cfg, cfg.DBName)
+}
+
+// RunStarMigrations corre las migraciones del esquema estrella, que vive en su propia base
+func RunStarMigrations(cfg DBConfig) {
+	runMigrations("file://internal/db/migrations_star", cfg, cfg.DBSTATS)
+}
+
+func runMigrations(source string, cfg DBConfig, dbName string) {
 	user := url.QueryEscape(cfg.User)
 	pass := url.QueryEscape(cfg.Password)
 	portInt := cfg.Port
 
 	migrateURL := fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=%s",
-		user, pass, cfg.Host, portInt, cfg.DBName, cfg.SSLMode,
+		user, pass, cfg.Host, portInt, dbName, cfg.SSLMode,
 	)
 
-	m, err := migrate.New("file://internal/db/migrations", migrateURL)
+	m, err := migrate.New(source, migrateURL)
 	if err != nil {
 		log.Fatalf("❌ Error creando migrator: %v", err)
 	}
 
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("❌ Error aplicando migraciones: %v", err)
+		log.Fatalf("❌ Error aplicando migraciones de %s: %v", dbName, err)
 	}
 
-	log.Println("✅ Migraciones aplicadas correctamente")
+	log.Printf("✅ Migraciones de %s aplicadas correctamente", dbName)
 }
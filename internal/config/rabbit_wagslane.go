@@ -52,3 +52,8 @@ func RabbitPublisher(mq MQConfig) (*rabbitmq.Publisher, string, error) {
 	)
 	return pub, url, err
 }
+
+// RabbitDial abre una conexión AMQP simple, sin reconexión, para declarar la topología al arrancar
+func RabbitDial(mq MQConfig) (*amqp.Connection, error) {
+	return amqp.DialTLS(mqURL(mq), tlsConfig())
+}
@@ -0,0 +1,15 @@
+package config
+
+// ReviewConfig define cuándo una línea extraída queda marcada para revisión obligatoria
+// y desde qué puntaje se enlaza sola a un producto existente
+type ReviewConfig struct {
+	ConfidenceThreshold float64
+	SkuMatchThreshold   float64
+}
+
+func LoadReviewConfig() ReviewConfig {
+	return ReviewConfig{
+		ConfidenceThreshold: getEnvFloat("REVIEW_CONFIDENCE_THRESHOLD", 0.8),
+		SkuMatchThreshold:   getEnvFloat("SKU_MATCH_THRESHOLD", 0.85),
+	}
+}
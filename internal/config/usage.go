@@ -0,0 +1,26 @@
+package config
+
+// UsageConfig define los precios con que se estima el costo de cada llamada y la cuota mensual por defecto.
+// Las cuotas en 0 son sin límite; una cuenta puede tener su propia cuota en usage_quota.
+type UsageConfig struct {
+	TextractPricePerPage float64
+	// LLMInputPer1K y LLMOutputPer1K reemplazan la tabla de precios de los modelos conocidos cuando son mayores a 0
+	LLMInputPer1K  float64
+	LLMOutputPer1K float64
+
+	MonthlyPages  int
+	MonthlyTokens int
+	MonthlyCost   float64
+}
+
+func LoadUsageConfig() UsageConfig {
+	return UsageConfig{
+		TextractPricePerPage: getEnvFloat("TEXTRACT_PRICE_PER_PAGE", 0.065),
+		LLMInputPer1K:        getEnvFloat("LLM_PRICE_INPUT_PER_1K", 0),
+		LLMOutputPer1K:       getEnvFloat("LLM_PRICE_OUTPUT_PER_1K", 0),
+
+		MonthlyPages:  getEnvInt("USAGE_MONTHLY_PAGES", 0),
+		MonthlyTokens: getEnvInt("USAGE_MONTHLY_TOKENS", 0),
+		MonthlyCost:   getEnvFloat("USAGE_MONTHLY_COST", 0),
+	}
+}
@@ -0,0 +1,15 @@
+CREATE TABLE if not exists outbox_event
+(
+    id              uuid PRIMARY KEY,
+    routing_key     varchar     NOT NULL,
+    payload         jsonb       NOT NULL,
+    headers         jsonb,
+    status          varchar(20) NOT NULL DEFAULT 'pending',
+    attempts        integer     NOT NULL DEFAULT 0,
+    last_error      varchar,
+    next_attempt_at timestamp   NOT NULL DEFAULT now(),
+    created_at      timestamp   NOT NULL DEFAULT now(),
+    sent_at         timestamp
+);
+
+CREATE INDEX if not exists idx_outbox_event_pending ON outbox_event (status, next_attempt_at);
@@ -0,0 +1,7 @@
+CREATE TABLE if not exists processed_event
+(
+    event_id     varchar   NOT NULL,
+    request_id   uuid      NOT NULL,
+    processed_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (event_id, request_id)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE if not exists request_status_history
+(
+    id          uuid PRIMARY KEY,
+    request_id  uuid        NOT NULL references request (id),
+    from_status varchar(50),
+    to_status   varchar(50) NOT NULL,
+    changed_by  varchar     NOT NULL,
+    reason      varchar,
+    created_at  timestamp   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX if not exists idx_request_status_history_request ON request_status_history (request_id, created_at);
@@ -0,0 +1,16 @@
+CREATE TABLE if not exists request_attempt
+(
+    id               uuid PRIMARY KEY,
+    request_id       uuid        NOT NULL references request (id),
+    attempt          integer     NOT NULL,
+    model            varchar,
+    prompt_version   varchar,
+    status           varchar(20) NOT NULL,
+    lines            integer     NOT NULL DEFAULT 0,
+    error            varchar,
+    created_products jsonb,
+    started_at       timestamp   NOT NULL DEFAULT now(),
+    finished_at      timestamp
+);
+
+CREATE INDEX if not exists idx_request_attempt_request ON request_attempt (request_id, attempt);
@@ -0,0 +1,18 @@
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS file_name varchar default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS content_type varchar default null;
+
+CREATE TABLE if not exists request_line
+(
+    id               uuid PRIMARY KEY,
+    request_id       uuid      NOT NULL references request (id),
+    movement_id      uuid      NOT NULL,
+    product_id       uuid      NOT NULL,
+    name             varchar   NOT NULL,
+    count            integer   NOT NULL,
+    skus             jsonb,
+    source_documents jsonb,
+    created_at       timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX if not exists idx_request_line_request ON request_line (request_id);
@@ -0,0 +1,11 @@
+CREATE TABLE if not exists column_mapping
+(
+    id                uuid PRIMARY KEY,
+    client_account_id uuid      NOT NULL,
+    name_column       varchar,
+    quantity_column   varchar   NOT NULL,
+    sku_column        varchar,
+    updated_at        timestamp NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX if not exists uq_column_mapping_client ON column_mapping (client_account_id);
@@ -0,0 +1,17 @@
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS supplier_name varchar default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS supplier_tax_id varchar default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS invoice_folio varchar default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS issue_date date default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS currency varchar(10) default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS net_amount numeric(18, 2) default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS tax_amount numeric(18, 2) default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS total_amount numeric(18, 2) default null;
+
+CREATE INDEX if not exists idx_documents_invoice_folio ON documents (invoice_folio);
@@ -0,0 +1,16 @@
+CREATE TABLE if not exists document_extraction
+(
+    id                 uuid PRIMARY KEY,
+    document_id        uuid      NOT NULL references documents (id),
+    request_attempt_id uuid      NOT NULL references request_attempt (id),
+    attempt            integer   NOT NULL,
+    model              varchar   NOT NULL,
+    output             text,
+    errors             jsonb,
+    valid              boolean   NOT NULL,
+    input_tokens       integer   NOT NULL DEFAULT 0,
+    output_tokens      integer   NOT NULL DEFAULT 0,
+    created_at         timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX if not exists idx_document_extraction_document ON document_extraction (document_id);
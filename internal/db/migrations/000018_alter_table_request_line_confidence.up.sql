@@ -0,0 +1,5 @@
+ALTER TABLE request_line ADD COLUMN IF NOT EXISTS confidence numeric(4, 3) default null;
+
+ALTER TABLE request_line ADD COLUMN IF NOT EXISTS reason_codes jsonb default null;
+
+ALTER TABLE request_line ADD COLUMN IF NOT EXISTS needs_review boolean NOT NULL default false;
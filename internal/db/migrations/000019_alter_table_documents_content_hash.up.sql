@@ -0,0 +1,10 @@
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS content_hash varchar(64) default null;
+
+CREATE INDEX if not exists idx_documents_content_hash ON documents (content_hash);
+
+CREATE TABLE if not exists extraction_cache
+(
+    key        varchar PRIMARY KEY,
+    payload    jsonb     NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now()
+);
@@ -0,0 +1,26 @@
+CREATE TABLE if not exists prompt_template
+(
+    id                uuid PRIMARY KEY,
+    use_case          varchar   NOT NULL,
+    version           varchar   NOT NULL,
+    client_account_id uuid,
+    template          text      NOT NULL,
+    active            boolean   NOT NULL DEFAULT false,
+    created_by        varchar,
+    created_at        timestamp NOT NULL DEFAULT now(),
+    activated_at      timestamp
+);
+
+CREATE UNIQUE INDEX if not exists uq_prompt_template_version
+    ON prompt_template (use_case, version, COALESCE(client_account_id, '00000000-0000-0000-0000-000000000000'));
+
+-- una sola versión activa por caso de uso (global) y por caso de uso y cliente
+CREATE UNIQUE INDEX if not exists uq_prompt_template_active
+    ON prompt_template (use_case, COALESCE(client_account_id, '00000000-0000-0000-0000-000000000000'))
+    WHERE active;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS prompt_version varchar default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS model_id varchar default null;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS raw_output text default null;
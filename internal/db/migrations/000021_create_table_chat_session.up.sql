@@ -0,0 +1,26 @@
+CREATE TABLE if not exists chat_session
+(
+    id                uuid PRIMARY KEY,
+    client_account_id uuid      NOT NULL,
+    user_id           varchar   NOT NULL,
+    title             varchar   NOT NULL,
+    product_id        uuid references product (id) ON DELETE SET NULL,
+    created_at        timestamp NOT NULL DEFAULT now(),
+    updated_at        timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX if not exists idx_chat_session_owner ON chat_session (client_account_id, user_id, updated_at DESC);
+
+CREATE TABLE if not exists chat_message
+(
+    id            uuid PRIMARY KEY,
+    session_id    uuid      NOT NULL references chat_session (id) ON DELETE CASCADE,
+    role          varchar   NOT NULL,
+    content       text      NOT NULL,
+    model         varchar,
+    input_tokens  integer   NOT NULL DEFAULT 0,
+    output_tokens integer   NOT NULL DEFAULT 0,
+    created_at    timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX if not exists idx_chat_message_session ON chat_message (session_id, created_at);
@@ -0,0 +1,26 @@
+CREATE TABLE if not exists usage_record
+(
+    id                uuid PRIMARY KEY,
+    client_account_id uuid           NOT NULL,
+    request_id        uuid references request (id),
+    kind              varchar        NOT NULL,
+    operation         varchar        NOT NULL,
+    model             varchar,
+    input_tokens      integer        NOT NULL DEFAULT 0,
+    output_tokens     integer        NOT NULL DEFAULT 0,
+    pages             integer        NOT NULL DEFAULT 0,
+    estimated_cost    numeric(12, 6) NOT NULL DEFAULT 0,
+    created_at        timestamp      NOT NULL DEFAULT now()
+);
+
+CREATE INDEX if not exists idx_usage_record_client ON usage_record (client_account_id, created_at);
+CREATE INDEX if not exists idx_usage_record_created ON usage_record (created_at);
+
+CREATE TABLE if not exists usage_quota
+(
+    client_account_id uuid PRIMARY KEY,
+    monthly_pages     integer        NOT NULL DEFAULT 0,
+    monthly_tokens    integer        NOT NULL DEFAULT 0,
+    monthly_cost      numeric(12, 2) NOT NULL DEFAULT 0,
+    updated_at        timestamp      NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE request_line ADD COLUMN IF NOT EXISTS candidates jsonb default null;
+
+CREATE INDEX IF NOT EXISTS idx_product_client_account ON product (client_account_id);
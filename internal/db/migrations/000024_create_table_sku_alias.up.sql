@@ -0,0 +1,17 @@
+CREATE TABLE if not exists sku_alias
+(
+    id                uuid PRIMARY KEY,
+    client_account_id uuid      NOT NULL,
+    supplier_tax_id   varchar,
+    alias             varchar   NOT NULL,
+    alias_normalized  varchar   NOT NULL,
+    product_id        uuid      NOT NULL references product (id) ON DELETE CASCADE,
+    sku_id            uuid references sku (id) ON DELETE SET NULL,
+    created_by        varchar,
+    created_at        timestamp NOT NULL DEFAULT now(),
+    updated_at        timestamp NOT NULL DEFAULT now()
+);
+
+-- un alias apunta a un solo producto por cliente y proveedor; sin proveedor vale para todas las facturas del cliente
+CREATE UNIQUE INDEX if not exists uq_sku_alias ON sku_alias (client_account_id, COALESCE(supplier_tax_id, ''), alias_normalized);
+CREATE INDEX if not exists idx_sku_alias_product ON sku_alias (product_id);
@@ -0,0 +1,6 @@
+-- una sesión no debe impedir borrar el producto que creó una solicitud al reprocesarla o confirmarla:
+-- la sesión queda como conversación general
+ALTER TABLE chat_session DROP CONSTRAINT IF EXISTS chat_session_product_id_fkey;
+
+ALTER TABLE chat_session
+    ADD CONSTRAINT chat_session_product_id_fkey FOREIGN KEY (product_id) REFERENCES product (id) ON DELETE SET NULL;
@@ -0,0 +1,18 @@
+ALTER TABLE fact_product_movement ADD COLUMN IF NOT EXISTS movimiento_uuid uuid;
+
+-- las reentregas del outbox pudieron cargar dos veces el mismo movimiento; se conserva la primera fila
+DELETE FROM fact_product_movement f
+    USING fact_product_movement d
+WHERE f.movimiento_uuid = d.movimiento_uuid
+  AND f.id > d.id;
+
+CREATE UNIQUE INDEX if not exists ux_fact_product_movement_movimiento
+    ON fact_product_movement (movimiento_uuid) WHERE movimiento_uuid IS NOT NULL;
+
+-- mensajes del outbox ya aplicados por el ETL, para descartar reentregas de ajustes
+CREATE TABLE if not exists etl_processed_message
+(
+    message_id   varchar   PRIMARY KEY,
+    routing_key  varchar   NOT NULL,
+    processed_at timestamp NOT NULL DEFAULT now()
+);
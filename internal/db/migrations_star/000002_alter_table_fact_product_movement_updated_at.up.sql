@@ -0,0 +1,2 @@
+-- momento del último ajuste del movimiento (confirmación con cambios o reasignación de producto)
+ALTER TABLE fact_product_movement ADD COLUMN IF NOT EXISTS updated_at timestamp;
@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateChatSessionDto abre una sesión; con product_id la conversación usa el historial de ese producto
+type CreateChatSessionDto struct {
+	Title     string     `json:"title"`
+	ProductId *uuid.UUID `json:"product_id"`
+}
+
+type ChatSessionDto struct {
+	ID        uuid.UUID        `json:"id"`
+	Title     string           `json:"title"`
+	ProductId *uuid.UUID       `json:"product_id,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	Messages  []ChatMessageDto `json:"messages,omitempty"`
+}
+
+type ChatMessageRequestDto struct {
+	Content string `json:"content"`
+}
+
+type ChatMessageDto struct {
+	ID           uuid.UUID `json:"id"`
+	Role         string    `json:"role"`
+	Content      string    `json:"content"`
+	Model        string    `json:"model,omitempty"`
+	InputTokens  int       `json:"input_tokens,omitempty"`
+	OutputTokens int       `json:"output_tokens,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ChatStreamTextDto es el payload de cada evento "token" del stream
+type ChatStreamTextDto struct {
+	Text string `json:"text"`
+}
+
+type ChatUsageDto struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// ChatStreamDoneDto es el payload del evento final "done": el mensaje guardado y el consumo de tokens
+type ChatStreamDoneDto struct {
+	Message ChatMessageDto `json:"message"`
+	Usage   ChatUsageDto   `json:"usage"`
+}
+
+// CatalogAnswerDto es la respuesta del chatbot de catálogo; Sources son los productos y solicitudes que cita
+type CatalogAnswerDto struct {
+	Answer  string             `json:"answer"`
+	Sources []CatalogSourceDto `json:"sources"`
+	Start   time.Time          `json:"start"`
+	End     time.Time          `json:"end"`
+	Usage   ChatUsageDto       `json:"usage"`
+}
+
+type CatalogSourceDto struct {
+	Type  string    `json:"type"`
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+}
@@ -9,13 +9,19 @@ import (
 )
 
 type CreateRequestDto struct {
-	File            multipart.File
+	Files           []UploadFile
 	RequestType     string
 	Type            TypeStatus
-	FileName        string
-	FileSize        int64
-	FileType        string
 	ClientAccountId uuid.UUID
+	// Force permite cargar archivos que el cliente ya había subido en otra solicitud
+	Force bool
+}
+
+type UploadFile struct {
+	File     multipart.File
+	FileName string
+	FileSize int64
+	FileType string
 }
 
 type RequestListDto struct {
@@ -25,6 +31,7 @@ type RequestListDto struct {
 	CreatedAt       time.Time            `json:"created_at"`
 	UpdatedAt       time.Time            `json:"updated_at"`
 	ClientAccountId uuid.UUID            `json:"client_account_id"`
+	Folio           string               `json:"folio,omitempty"`
 }
 
 type RequestDto struct {
@@ -34,18 +41,73 @@ type RequestDto struct {
 	CreatedAt       time.Time            `json:"created_at"`
 	UpdatedAt       time.Time            `json:"updated_at"`
 	ClientAccountId uuid.UUID            `json:"client_account_id"`
+	Invoice         *InvoiceHeaderDto    `json:"invoice,omitempty"`
+	Documents       []DocumentDto        `json:"documents"`
 	Movements       []Movements          `json:"movements"`
 }
 
+// SupplierSummaryDto son las solicitudes y unidades recibidas de un proveedor en un período
+type SupplierSummaryDto struct {
+	Supplier string `json:"supplier"`
+	Requests int64  `json:"requests"`
+	Units    int64  `json:"units"`
+}
+
+type DocumentDto struct {
+	ID          uuid.UUID         `json:"id"`
+	FileName    string            `json:"file_name"`
+	ContentHash string            `json:"content_hash,omitempty"`
+	Invoice     *InvoiceHeaderDto `json:"invoice,omitempty"`
+	// PromptVersion y ModelID indican con qué prompt y modelo se extrajo el documento
+	PromptVersion string    `json:"prompt_version,omitempty"`
+	ModelID       string    `json:"model_id,omitempty"`
+	RawOutput     string    `json:"raw_output,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// InvoiceHeaderDto es la cabecera de la factura de donde salió la solicitud
+type InvoiceHeaderDto struct {
+	SupplierName  string     `json:"supplier_name,omitempty"`
+	SupplierTaxId string     `json:"supplier_tax_id,omitempty"`
+	Folio         string     `json:"folio,omitempty"`
+	IssueDate     *time.Time `json:"issue_date,omitempty"`
+	Currency      string     `json:"currency,omitempty"`
+	Net           *float64   `json:"net,omitempty"`
+	Tax           *float64   `json:"tax,omitempty"`
+	Total         *float64   `json:"total,omitempty"`
+}
+
+type RequestStatusHistoryDto struct {
+	From      models.RequestStatus `json:"from"`
+	To        models.RequestStatus `json:"to"`
+	ChangedBy string               `json:"changed_by"`
+	Reason    string               `json:"reason"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
 type Movements struct {
-	Id             uuid.UUID `json:"id"`
-	ProductId      uuid.UUID `json:"productId"`
-	Nombre         string    `json:"nombre"`
-	MovementTypeId int       `json:"movementTypeId"`
-	Count          int       `json:"count"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	TypeMovement   int       `json:"type_movement"`
+	Id             uuid.UUID   `json:"id"`
+	ProductId      uuid.UUID   `json:"productId"`
+	Nombre         string      `json:"nombre"`
+	MovementTypeId int         `json:"movementTypeId"`
+	Count          int         `json:"count"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	TypeMovement   int         `json:"type_movement"`
+	Sources        []uuid.UUID `json:"source_documents,omitempty"`
+	Confidence     *float64    `json:"confidence,omitempty"`
+	ReasonCodes    []string    `json:"reason_codes,omitempty"`
+	NeedsReview    bool        `json:"needs_review"`
+	// Candidates son los productos de la cuenta que podrían ser el de la línea, del más al menos probable
+	Candidates []SkuCandidateDto `json:"candidates,omitempty"`
+}
+
+type SkuCandidateDto struct {
+	ProductId   uuid.UUID `json:"productId"`
+	ProductName string    `json:"product_name"`
+	Sku         string    `json:"sku,omitempty"`
+	Score       float64   `json:"score"`
+	Method      string    `json:"method"`
 }
 
 type ProductDto struct {
@@ -57,6 +119,39 @@ type ProductDto struct {
 	Status      string    `json:"status"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	Skus        []SkuDto  `json:"skus,omitempty"`
+}
+
+// CreateProductDto da de alta un producto sin stock; el stock solo cambia con movimientos
+type CreateProductDto struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Referencial *uuid.UUID `json:"referencial_id"`
+	Status      string     `json:"status"`
+	Skus        []string   `json:"skus"`
+}
+
+// UpdateProductDto cambia solo los campos que vienen informados
+type UpdateProductDto struct {
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	Referencial *uuid.UUID `json:"referencial_id"`
+	Status      *string    `json:"status"`
+}
+
+type SkuDto struct {
+	ID        uuid.UUID `json:"id"`
+	NameSku   string    `json:"name_sku"`
+	Status    bool      `json:"status"`
+	ProductId uuid.UUID `json:"productId"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaveSkuDto crea o modifica un SKU de un producto; sin status un SKU nuevo queda activo
+type SaveSkuDto struct {
+	NameSku string `json:"name_sku"`
+	Status  *bool  `json:"status"`
 }
 
 type TypeStatus int
@@ -117,12 +212,14 @@ func GetTypeMovementForDeltaUpdate(movement int) int {
 
 }
 
-func (s CreateRequestDto) GetTypeStatus() int {
-	return int(s.Type) + 1
+// MovementTypeId devuelve el id de movements_type (1 entrada, 2 salida)
+func (t TypeStatus) MovementTypeId() int {
+	return int(t) + 1
 }
 
-func (s CreateRequestDto) GetMovementToUpOrLessStock() int {
-	switch s.Type {
+// Sign devuelve el signo con que el tipo afecta el stock
+func (t TypeStatus) Sign() int {
+	switch t {
 	case TypeStatusIn:
 		return 1
 	case TypeStatusOut:
@@ -132,6 +229,27 @@ func (s CreateRequestDto) GetMovementToUpOrLessStock() int {
 	}
 }
 
+func (s CreateRequestDto) GetTypeStatus() int {
+	return s.Type.MovementTypeId()
+}
+
+func (s CreateRequestDto) GetMovementToUpOrLessStock() int {
+	return s.Type.Sign()
+}
+
+// CreateManualRequestDto crea una solicitud directo desde sus líneas, sin documento
+type CreateManualRequestDto struct {
+	Type  string          `json:"type"`
+	Items []ManualItemDto `json:"items"`
+}
+
+// ManualItemDto identifica el producto por id o por sku
+type ManualItemDto struct {
+	ProductId uuid.UUID `json:"productId"`
+	Sku       string    `json:"sku"`
+	Count     int       `json:"count"`
+}
+
 var TypeMovement = map[int]int{
 	-1: 2,
 	1:  1,
@@ -145,6 +263,8 @@ type Page[T any] struct {
 	TotalPages int   `json:"total_pages"`
 }
 
+// MovementsPatch corrige un movimiento en la confirmación. Un ProductId distinto al actual reasigna la línea
+// a ese producto y guarda los códigos leídos como alias para las próximas facturas.
 type MovementsPatch struct {
 	Id             uuid.UUID `json:"id"`
 	Count          int       `json:"count"`
@@ -153,6 +273,53 @@ type MovementsPatch struct {
 	Deleted        bool      `json:"deleted"`
 }
 
+type CancelRequestDto struct {
+	Reason string `json:"reason"`
+}
+
+type ReprocessRequestDto struct {
+	Model         string `json:"model"`
+	PromptVersion string `json:"prompt_version"`
+}
+
+type RequestAttemptDto struct {
+	Attempt       int        `json:"attempt"`
+	Model         string     `json:"model"`
+	PromptVersion string     `json:"prompt_version"`
+	Status        string     `json:"status"`
+	Lines         int        `json:"lines"`
+	Error         string     `json:"error,omitempty"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+}
+
+// ColumnMappingDto indica por nombre de encabezado las columnas de las planillas CSV/XLSX del cliente
+type ColumnMappingDto struct {
+	NameColumn     string    `json:"name_column"`
+	QuantityColumn string    `json:"quantity_column"`
+	SkuColumn      string    `json:"sku_column"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// SkuAliasDto es un código o descripción de proveedor que se resuelve directo a un producto de la cuenta
+type SkuAliasDto struct {
+	ID            uuid.UUID  `json:"id"`
+	Alias         string     `json:"alias"`
+	SupplierTaxId *string    `json:"supplier_tax_id,omitempty"`
+	ProductId     uuid.UUID  `json:"productId"`
+	SkuId         *uuid.UUID `json:"sku_id,omitempty"`
+	CreatedBy     string     `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// CreateSkuAliasDto crea o reasigna un alias; sin supplier_tax_id vale para todos los proveedores del cliente
+type CreateSkuAliasDto struct {
+	Alias         string    `json:"alias"`
+	SupplierTaxId *string   `json:"supplier_tax_id"`
+	ProductId     uuid.UUID `json:"productId"`
+}
+
 type RequestPatch struct {
 	Id        uuid.UUID        `json:"id"`
 	Movements []MovementsPatch `json:"movements"`
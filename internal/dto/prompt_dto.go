@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PromptTemplateDto struct {
+	ID              uuid.UUID  `json:"id"`
+	UseCase         string     `json:"use_case"`
+	Version         string     `json:"version"`
+	ClientAccountId *uuid.UUID `json:"client_account_id,omitempty"`
+	Template        string     `json:"template"`
+	Active          bool       `json:"active"`
+	CreatedBy       string     `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ActivatedAt     *time.Time `json:"activated_at,omitempty"`
+}
+
+// CreatePromptTemplateDto crea una versión; sin client_account_id es global. Activate la deja activa al crearla.
+type CreatePromptTemplateDto struct {
+	UseCase         string     `json:"use_case"`
+	Version         string     `json:"version"`
+	ClientAccountId *uuid.UUID `json:"client_account_id"`
+	Template        string     `json:"template"`
+	Activate        bool       `json:"activate"`
+}
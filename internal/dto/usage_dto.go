@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageTotalsDto suma el consumo de un grupo de llamadas; EstimatedCost está en USD
+type UsageTotalsDto struct {
+	TextractPages int     `json:"textract_pages"`
+	LLMCalls      int     `json:"llm_calls"`
+	InputTokens   int     `json:"input_tokens"`
+	OutputTokens  int     `json:"output_tokens"`
+	EstimatedCost float64 `json:"estimated_cost"`
+}
+
+type UsageByDayDto struct {
+	Day string `json:"day"`
+	UsageTotalsDto
+}
+
+type UsageByClientDto struct {
+	ClientAccountId uuid.UUID `json:"client_account_id"`
+	UsageTotalsDto
+}
+
+type UsageReportDto struct {
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	Total    UsageTotalsDto     `json:"total"`
+	ByDay    []UsageByDayDto    `json:"by_day"`
+	ByClient []UsageByClientDto `json:"by_client"`
+}
+
+// UsageQuotaDto son los límites mensuales de una cuenta; 0 es sin límite
+type UsageQuotaDto struct {
+	ClientAccountId uuid.UUID `json:"client_account_id"`
+	MonthlyPages    int       `json:"monthly_pages"`
+	MonthlyTokens   int       `json:"monthly_tokens"`
+	MonthlyCost     float64   `json:"monthly_cost"`
+}
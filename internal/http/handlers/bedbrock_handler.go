@@ -9,11 +9,56 @@ import (
 	"github.com/google/uuid"
 	"github.com/stock-ahora/api-stock/internal/models"
 	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/chatbot"
+	"github.com/stock-ahora/api-stock/internal/service/prompt"
+	"github.com/stock-ahora/api-stock/internal/service/usage"
 	"gorm.io/gorm"
 )
 
 type BedbrockHandler struct {
-	Db *gorm.DB
+	Db      *gorm.DB
+	LLM     *bedrock.Service
+	Prompts prompt.Registry
+	Chat    chatbot.Service
+	Usage   usage.Service
+}
+
+// ConsultaCatalogo responde sobre todo el catálogo de la cuenta del encabezado, con las fuentes citadas.
+// Query params: queryClient (la pregunta), start y end (YYYY-MM-DD, por defecto los últimos 90 días).
+func (h *BedbrockHandler) ConsultaCatalogo(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), chatMessageTimeout)
+	defer cancel()
+
+	clientAccountID, err, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	if err != nil {
+		http.Error(w, "X-Client-Account-Id inválido", http.StatusBadRequest)
+		return
+	}
+
+	start, end, err := parseDateParams(r)
+	if err != nil {
+		http.Error(w, "start y end deben tener formato YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(chatMessageTimeout))
+
+	answer, err := h.Chat.AskCatalog(ctx, clientAccountID, chatbot.CatalogQuestion{
+		Question: r.URL.Query().Get("queryClient"),
+		Start:    start,
+		End:      end,
+	})
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(answer)
 }
 
 func (h *BedbrockHandler) ConsultaProductos(w http.ResponseWriter, r *http.Request) {
@@ -32,14 +77,26 @@ func (h *BedbrockHandler) ConsultaProductos(w http.ResponseWriter, r *http.Reque
 
 	requestClient := r.URL.Query().Get("queryClient")
 
-	svc := bedrock.NewBedrockService(context.Background(), bedrock.NOVA_PRO_AWS, "us-east-1")
-
 	movements, product, _ := h.listLast2MonthsMovements(r.Context(), productID)
 
 	movementsStr, _ := ToJSON(movements)
 	productStr, _ := ToJSON(product)
 
-	resultChatbot, _ := svc.ChatBot(context.Background(), bedrock.ChatBot, movementsStr+"\n"+productStr+"\nPregunta: "+requestClient)
+	// sin cliente en el encabezado se usa la versión global
+	clientAccountId, _ := uuid.Parse(r.Header.Get("X-Client-Account-Id"))
+	chatPrompt, err := h.Prompts.Resolve(r.Context(), prompt.UseCaseChatbot, clientAccountId, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resultChatbot, _ := h.LLM.ChatBot(r.Context(), movementsStr+"\n"+productStr+"\nPregunta: "+requestClient, chatPrompt.Template)
+	if tokens, ok := resultChatbot["usage"].(map[string]interface{}); ok && product.ClientAccount != uuid.Nil {
+		inputTokens, _ := tokens["inputTokens"].(int)
+		outputTokens, _ := tokens["outputTokens"].(int)
+		h.Usage.RecordLLM(r.Context(), product.ClientAccount, nil, usage.OperationChatbot, h.LLM.Model(),
+			bedrock.Usage{InputTokens: inputTokens, OutputTokens: outputTokens})
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resultChatbot)
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/service/chatbot"
+)
+
+// chatMessageTimeout cubre la llamada al modelo, que es bastante más lenta que una consulta a la base
+const chatMessageTimeout = 60 * time.Second
+
+type ChatSessionHandler struct {
+	Service chatbot.Service
+}
+
+func (h *ChatSessionHandler) Create(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	var reqBody dto.CreateChatSessionDto
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	session, err := h.Service.CreateSession(ctx, clientAccountID, getActorHeader(r), reqBody)
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+func (h *ChatSessionHandler) List(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	sessions, err := h.Service.ListSessions(ctx, clientAccountID, getActorHeader(r))
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (h *ChatSessionHandler) Get(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := h.Service.GetSession(ctx, clientAccountID, getActorHeader(r), id)
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func (h *ChatSessionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteSession(ctx, clientAccountID, getActorHeader(r), id); err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChatSessionHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), chatMessageTimeout)
+	defer cancel()
+
+	// el WriteTimeout del servidor es menor que lo que puede tardar el modelo
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(chatMessageTimeout))
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var reqBody dto.ChatMessageRequestDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	answer, err := h.Service.SendMessage(ctx, clientAccountID, getActorHeader(r), id, reqBody)
+	if err != nil {
+		writeChatError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(answer)
+}
+
+// SendMessageStream responde con Server-Sent Events: un evento "token" por fragmento ({"text": ...}),
+// y al final "done" con el mensaje guardado y el consumo, o "error". Si el cliente se desconecta, r.Context()
+// se cancela y con él la llamada al modelo.
+func (h *ChatSessionHandler) SendMessageStream(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), chatMessageTimeout)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var reqBody dto.ChatMessageRequestDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(chatMessageTimeout)); err != nil {
+		log.Printf("No se pudo extender el plazo de escritura del stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, payload any) error {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, raw); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	answer, err := h.Service.SendMessageStream(ctx, clientAccountID, getActorHeader(r), id, reqBody, func(text string) error {
+		return send("token", dto.ChatStreamTextDto{Text: text})
+	})
+	if err != nil {
+		if r.Context().Err() == nil {
+			_ = send("error", map[string]string{"error": err.Error()})
+		}
+		return
+	}
+
+	_ = send("done", dto.ChatStreamDoneDto{
+		Message: answer,
+		Usage: dto.ChatUsageDto{
+			InputTokens:  answer.InputTokens,
+			OutputTokens: answer.OutputTokens,
+			TotalTokens:  answer.InputTokens + answer.OutputTokens,
+		},
+	})
+}
+
+func writeChatError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, chatbot.ErrSessionNotFound), errors.Is(err, chatbot.ErrProductNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, chatbot.ErrInvalidMessage):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
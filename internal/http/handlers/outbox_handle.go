@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+)
+
+type OutboxHandler struct {
+	Outbox *eventservice.Outbox
+}
+
+// ListStuck lista los eventos del outbox que no han podido publicarse.
+// Query params: minAttempts (default 3), olderThanMinutes (default 5), limit (default 100).
+func (h *OutboxHandler) ListStuck(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	q := r.URL.Query()
+	minAttempts := queryInt(q.Get("minAttempts"), 3)
+	olderThan := time.Duration(queryInt(q.Get("olderThanMinutes"), 5)) * time.Minute
+	limit := queryInt(q.Get("limit"), 100)
+
+	events, err := h.Outbox.ListStuck(ctx, minAttempts, olderThan, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]dto.OutboxEventDto, 0, len(events))
+	for _, evt := range events {
+		items = append(items, dto.OutboxEventDto{
+			ID:            evt.ID,
+			RoutingKey:    evt.RoutingKey,
+			Payload:       json.RawMessage(evt.Payload),
+			Status:        string(evt.Status),
+			Attempts:      evt.Attempts,
+			LastError:     evt.LastError,
+			NextAttemptAt: evt.NextAttemptAt,
+			CreatedAt:     evt.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+func queryInt(value string, def int) int {
+	if v, err := strconv.Atoi(value); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/service/prompt"
+)
+
+type PromptHandler struct {
+	Registry prompt.Registry
+}
+
+// List lista las versiones de prompts; query param use_case filtra por caso de uso
+func (h *PromptHandler) List(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	prompts, err := h.Registry.List(ctx, r.URL.Query().Get("use_case"))
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prompts)
+}
+
+func (h *PromptHandler) Create(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	var reqBody dto.CreatePromptTemplateDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.Registry.Create(ctx, getActorHeader(r), reqBody)
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *PromptHandler) Activate(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	activated, err := h.Registry.Activate(ctx, id)
+	if err != nil {
+		writePromptError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(activated)
+}
+
+func writePromptError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, prompt.ErrPromptNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, prompt.ErrInvalidPrompt):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, prompt.ErrDuplicateVersion):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -3,16 +3,21 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/stock-ahora/api-stock/internal/dto"
 	"github.com/stock-ahora/api-stock/internal/service/request"
+	"github.com/stock-ahora/api-stock/internal/service/usage"
 )
 
 type RequestHandler struct {
@@ -27,7 +32,7 @@ func (h *RequestHandler) List(w http.ResponseWriter, r *http.Request) {
 	clientAccountId, err, _ := getClientAccountIdHeader(w, r)
 	page, size := parsePagination(r)
 
-	requests, err := h.Service.List(ctx, clientAccountId, page, size)
+	requests, err := h.Service.List(ctx, clientAccountId, r.URL.Query().Get("folio"), page, size)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -36,6 +41,16 @@ func (h *RequestHandler) List(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(requests)
 }
 
+const (
+	createBaseTimeout    = 5 * time.Second
+	createTimeoutPerFile = 10 * time.Second
+)
+
+// createTimeout da a la carga un plazo base más uno por archivo, que se hashea y se sube uno tras otro
+func createTimeout(files int) time.Duration {
+	return createBaseTimeout + time.Duration(files)*createTimeoutPerFile
+}
+
 func (h *RequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	clientAccountID, err, done := getClientAccountIdHeader(w, r)
@@ -55,30 +70,66 @@ func (h *RequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	file, fileHeader, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "Error al obtener el archivo: "+err.Error(), http.StatusBadRequest)
+	// se aceptan varios archivos en "files" y el campo "file" de siempre
+	fileHeaders := append(r.MultipartForm.File["file"], r.MultipartForm.File["files"]...)
+	if len(fileHeaders) == 0 {
+		http.Error(w, "Se requiere al menos un archivo en 'file' o 'files'", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	fileType := detectContentType(file, fileHeader)
+	uploads := make([]dto.UploadFile, 0, len(fileHeaders))
+	for _, fileHeader := range fileHeaders {
+		file, err := fileHeader.Open()
+		if err != nil {
+			http.Error(w, "Error al obtener el archivo: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		uploads = append(uploads, dto.UploadFile{
+			File:     file,
+			FileName: fileHeader.Filename,
+			FileSize: fileHeader.Size,
+			FileType: detectContentType(file, fileHeader),
+		})
+	}
+
+	force, _ := strconv.ParseBool(r.FormValue("force"))
 
 	requestDto := &dto.CreateRequestDto{
 		Type:            dto.ParseTypeStatus(requestType),
-		File:            file,
-		FileName:        fileHeader.Filename,
-		FileSize:        fileHeader.Size,
-		FileType:        fileType,
+		Files:           uploads,
 		ClientAccountId: clientAccountID,
+		Force:           force,
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	timeout := createTimeout(len(uploads))
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
 	defer cancel()
+	// el WriteTimeout del servidor es menor que el plazo de una carga con varios archivos
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + createBaseTimeout)); err != nil {
+		log.Printf("no se pudo extender el plazo de escritura: %v", err)
+	}
 
 	req, err := h.Service.Create(requestDto, ctx)
+	var duplicate *request.DuplicateDocumentError
+	if errors.As(err, &duplicate) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error":               duplicate.Error(),
+			"original_request_id": duplicate.OriginalRequestID,
+			"content_hash":        duplicate.ContentHash,
+		})
+		return
+	}
+	var quota *usage.QuotaExceededError
+	if errors.As(err, &quota) {
+		writeQuotaError(w, quota)
+		return
+	}
 	if err != nil {
-		http.Error(w, "messaging busy", http.StatusServiceUnavailable)
+		http.Error(w, "Error al crear la solicitud: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -89,6 +140,33 @@ func (h *RequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *RequestHandler) CreateManual(w http.ResponseWriter, r *http.Request) {
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	var reqBody dto.CreateManualRequestDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	req, err := h.Service.CreateManual(ctx, clientAccountID, getActorHeader(r), reqBody)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(req)
+}
+
 func getClientAccountIdHeader(w http.ResponseWriter, r *http.Request) (uuid.UUID, error, bool) {
 	clientAccountIDStr := r.Header.Get("X-Client-Account-Id")
 	clientAccountID, err := uuid.Parse(clientAccountIDStr)
@@ -99,11 +177,274 @@ func getClientAccountIdHeader(w http.ResponseWriter, r *http.Request) (uuid.UUID
 	return clientAccountID, err, false
 }
 
+// getActorHeader identifica al usuario que origina el cambio, para el historial de estados
+func getActorHeader(r *http.Request) string {
+	if actor := r.Header.Get("X-User-Id"); actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+func parseIdParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "UUID inválido", http.StatusBadRequest)
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+func (h *RequestHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var reqBody dto.CancelRequestDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(reqBody.Reason) == "" {
+		http.Error(w, "El campo 'reason' es obligatorio", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.Cancel(ctx, clientAccountID, getActorHeader(r), id, reqBody.Reason); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message":"cancelled"}`))
+}
+
+func (h *RequestHandler) Reprocess(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	// el body es opcional: sin él se reprocesa con el modelo y prompt por defecto
+	var reqBody dto.ReprocessRequestDto
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.Service.Reprocess(ctx, clientAccountID, getActorHeader(r), id, reqBody); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"message":"reprocessing"}`))
+}
+
+func (h *RequestHandler) Attempts(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	attempts, err := h.Service.Attempts(ctx, clientAccountID, id)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attempts)
+}
+
+func (h *RequestHandler) History(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := h.Service.History(ctx, clientAccountID, id)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+func (h *RequestHandler) ColumnMapping(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	mapping, err := h.Service.ColumnMapping(ctx, clientAccountID)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapping)
+}
+
+func (h *RequestHandler) SaveColumnMapping(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	var reqBody dto.ColumnMappingDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	mapping, err := h.Service.SaveColumnMapping(ctx, clientAccountID, reqBody)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mapping)
+}
+
+// SkuAliases lista los alias de SKU de la cuenta; query param productId filtra por producto
+func (h *RequestHandler) SkuAliases(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	var productId uuid.UUID
+	if raw := r.URL.Query().Get("productId"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "productId inválido", http.StatusBadRequest)
+			return
+		}
+		productId = id
+	}
+
+	aliases, err := h.Service.SkuAliases(ctx, clientAccountID, productId)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(aliases)
+}
+
+func (h *RequestHandler) SaveSkuAlias(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	var reqBody dto.CreateSkuAliasDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	alias, err := h.Service.SaveSkuAlias(ctx, clientAccountID, getActorHeader(r), reqBody)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alias)
+}
+
+func (h *RequestHandler) DeleteSkuAlias(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteSkuAlias(ctx, clientAccountID, id); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *RequestHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
 
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
 	segments := strings.Split(r.URL.Path, "/")
 	idStr := segments[len(segments)-1] // última parte del path
 	id, err := uuid.Parse(idStr)
@@ -111,9 +452,9 @@ func (h *RequestHandler) Get(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "UUID inválido", http.StatusBadRequest)
 		return
 	}
-	req, err := h.Service.Get(ctx, id)
+	req, err := h.Service.Get(ctx, clientAccountID, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeRequestError(w, err)
 		return
 	}
 
@@ -144,9 +485,9 @@ func (h *RequestHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 2. Llamar al servicio con la info obtenida
-	err = h.Service.Confirm(clientAccountID, reqBody)
+	err = h.Service.Confirm(clientAccountID, getActorHeader(r), reqBody)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeRequestError(w, err)
 		return
 	}
 
@@ -155,12 +496,53 @@ func (h *RequestHandler) Update(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"message":"updated"}`))
 }
 
+// writeRequestError traduce los errores tipados del servicio de solicitudes a códigos HTTP
+func writeRequestError(w http.ResponseWriter, err error) {
+	var quota *usage.QuotaExceededError
+	if errors.As(err, &quota) {
+		writeQuotaError(w, quota)
+		return
+	}
+
+	switch {
+	case errors.Is(err, request.ErrRequestNotFound), errors.Is(err, request.ErrColumnMappingNotFound),
+		errors.Is(err, request.ErrSkuAliasNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, request.ErrInvalidReprocessOptions), errors.Is(err, request.ErrInvalidManualRequest),
+		errors.Is(err, request.ErrInvalidColumnMapping), errors.Is(err, request.ErrInvalidSkuAlias):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, request.ErrUnknownProduct):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, request.ErrMovementNotFound), errors.Is(err, request.ErrUncompensableLines):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.As(err, new(*request.ReviewRequiredError)):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.As(err, new(*request.TransitionError)), errors.Is(err, request.ErrNotReprocessable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeQuotaError responde 429 con el recurso agotado para que el cliente sepa por qué no se procesó
+func writeQuotaError(w http.ResponseWriter, quota *usage.QuotaExceededError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":    quota.Error(),
+		"resource": quota.Resource,
+		"used":     quota.Used,
+		"limit":    quota.Limit,
+	})
+}
+
 func detectContentType(file multipart.File, header *multipart.FileHeader) string {
 	if ct := header.Header.Get("Content-Type"); ct != "" {
 		return ct
 	}
 	buf := make([]byte, 512)
 	n, _ := file.Read(buf)
+	file.Seek(0, io.SeekStart) // el archivo se sube completo después
 	return http.DetectContentType(buf[:n])
 }
 
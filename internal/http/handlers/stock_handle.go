@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/dto"
 	"github.com/stock-ahora/api-stock/internal/service/stock"
 )
 
@@ -47,3 +53,216 @@ func (h *StockHandler) Get(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 
 }
+
+func (h *StockHandler) Create(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+
+	var reqBody dto.CreateProductDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.Service.CreateProduct(ctx, clientAccountID, reqBody)
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(product)
+}
+
+// Update cambia nombre, descripción, referencial_id o estado; los campos ausentes no se tocan
+func (h *StockHandler) Update(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var reqBody dto.UpdateProductDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.Service.UpdateProduct(ctx, clientAccountID, id, reqBody)
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func (h *StockHandler) Archive(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.Service.ArchiveProduct(ctx, clientAccountID, id)
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func (h *StockHandler) Skus(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	skus, err := h.Service.Skus(ctx, clientAccountID, id)
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(skus)
+}
+
+func (h *StockHandler) CreateSku(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var reqBody dto.SaveSkuDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sku, err := h.Service.CreateSku(ctx, clientAccountID, id, reqBody)
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sku)
+}
+
+func (h *StockHandler) UpdateSku(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+	skuId, err := uuid.Parse(chi.URLParam(r, "skuId"))
+	if err != nil {
+		http.Error(w, "UUID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var reqBody dto.SaveSkuDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sku, err := h.Service.UpdateSku(ctx, clientAccountID, id, skuId, reqBody)
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sku)
+}
+
+func (h *StockHandler) DeleteSku(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	clientAccountID, _, done := getClientAccountIdHeader(w, r)
+	if done {
+		return
+	}
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+	skuId, err := uuid.Parse(chi.URLParam(r, "skuId"))
+	if err != nil {
+		http.Error(w, "UUID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteSku(ctx, clientAccountID, id, skuId); err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStockError traduce los errores del catálogo de productos a códigos HTTP
+func writeStockError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, stock.ErrProductNotFound), errors.Is(err, stock.ErrSkuNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, stock.ErrInvalidProduct), errors.Is(err, stock.ErrInvalidSku):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, stock.ErrDuplicateSku):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/service/usage"
+)
+
+type UsageHandler struct {
+	Service usage.Service
+}
+
+// Report devuelve el consumo de Textract y del LLM por día y por cliente.
+// Query params: start y end (YYYY-MM-DD, ambos incluidos; por defecto el mes en curso) y client_account_id opcional.
+func (h *UsageHandler) Report(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	start, end, err := parseDateParams(r)
+	if err != nil {
+		http.Error(w, "start y end deben tener formato YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	if start.IsZero() {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	if end.IsZero() {
+		end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if end.Before(start) {
+		http.Error(w, "end no puede ser anterior a start", http.StatusBadRequest)
+		return
+	}
+
+	var clientAccountId *uuid.UUID
+	if raw := r.URL.Query().Get("client_account_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "client_account_id inválido", http.StatusBadRequest)
+			return
+		}
+		clientAccountId = &id
+	}
+
+	report, err := h.Service.Report(ctx, start, end.AddDate(0, 0, 1), clientAccountId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// SetQuota guarda los límites mensuales de la cuenta del path; 0 es sin límite
+func (h *UsageHandler) SetQuota(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	id, ok := parseIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var reqBody dto.UsageQuotaDto
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	reqBody.ClientAccountId = id
+
+	quota, err := h.Service.SetQuota(ctx, reqBody)
+	if err != nil {
+		if errors.Is(err, usage.ErrInvalidQuota) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quota)
+}
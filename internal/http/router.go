@@ -12,12 +12,17 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/stock-ahora/api-stock/internal/config"
 	"github.com/stock-ahora/api-stock/internal/service/Etl_service"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/chatbot"
+	"github.com/stock-ahora/api-stock/internal/service/dashboard"
 	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+	"github.com/stock-ahora/api-stock/internal/service/extractor"
 	"github.com/stock-ahora/api-stock/internal/service/movement"
+	"github.com/stock-ahora/api-stock/internal/service/prompt"
 	"github.com/stock-ahora/api-stock/internal/service/request"
 	"github.com/stock-ahora/api-stock/internal/service/s3"
 	"github.com/stock-ahora/api-stock/internal/service/stock"
-	"github.com/stock-ahora/api-stock/internal/service/textract"
+	"github.com/stock-ahora/api-stock/internal/service/usage"
 	"github.com/wagslane/go-rabbitmq"
 	"gorm.io/gorm"
 
@@ -32,8 +37,10 @@ const MovementPath = APIBasePath + "/movement"
 const NotificationPath = APIBasePath + "/notification"
 const ChatBot = APIBasePath + "/chatbot"
 const DashboardPath = "/prod/api/v1" + "/dashboard"
+const AdminPath = APIBasePath + "/admin"
+const UsagePath = APIBasePath + "/usage"
 
-func NewRouter(s3Config config.UploadService, db *gorm.DB, dbStarts *gorm.DB, _ any, _ any, region string, _ string, mqConfig config.MQConfig) *chi.Mux {
+func NewRouter(s3Svc s3.Storage, db *gorm.DB, dbStarts *gorm.DB, _ any, _ any, region string, _ string, mqConfig config.MQConfig) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"}, // ← acepta cualquier origen
@@ -46,8 +53,6 @@ func NewRouter(s3Config config.UploadService, db *gorm.DB, dbStarts *gorm.DB, _
 
 	r.Use(middleware.RequestID, middleware.Recoverer)
 	h := handlers.NewStatusHandler()
-	s3Svc := s3.NewS3Svs(s3.S3config{UploadService: s3Config})
-	stockSvc := stock.NewStockService(db)
 	movementSvc := movement.NewMovementService(db)
 
 	pub, urlConnectionMQ, err := config.RabbitPublisher(mqConfig)
@@ -56,24 +61,57 @@ func NewRouter(s3Config config.UploadService, db *gorm.DB, dbStarts *gorm.DB, _
 		pub = nil // degradamos, NO panic
 	}
 	eventService := eventservice.NewMQPublisher(pub, urlConnectionMQ)
+	stockSvc := stock.NewStockService(db, eventService)
+	outbox := eventservice.NewOutbox(db, eventService)
+	go outbox.Run(context.Background())
 
-	textractService := textract.NewTextractService(region)
-	requestService := request.NewRequestService(db, s3Svc, eventService, textractService, dbStarts)
+	llmCfg := config.LoadLLMConfig()
+	llmClient, err := bedrock.NewClient(context.Background(), llmCfg)
+	if err != nil {
+		log.Fatalf("❌ llm: %v", err)
+	}
+	llmService := bedrock.NewService(llmClient, llmCfg)
+
+	documentExtractor, err := extractor.New(config.LoadExtractorConfig(region), s3Svc, llmService, extractor.NewDBCache(db))
+	if err != nil {
+		log.Fatalf("❌ extractor: %v", err)
+	}
+	promptRegistry := prompt.NewRegistry(db)
+	usageSvc := usage.NewService(db, config.LoadUsageConfig())
+	reviewCfg := config.LoadReviewConfig()
+	requestService := request.NewRequestService(db, s3Svc, eventService, documentExtractor, promptRegistry, usageSvc, dbStarts, request.Settings{
+		DefaultModel:    llmService.Model(),
+		ReviewThreshold: reviewCfg.ConfidenceThreshold,
+		MatchThreshold:  reviewCfg.SkuMatchThreshold,
+	})
 	handleRequest := &handlers.RequestHandler{Service: requestService}
 	handleStock := &handlers.StockHandler{Service: stockSvc}
-	handleChatBot := &handlers.BedbrockHandler{Db: db}
-	habdleDashboard := &handlers.DashboardHandler{Db: dbStarts}
+	dashboardSvc := dashboard.NewDashboardService(dbStarts)
+	chatService := chatbot.NewService(db, llmService, promptRegistry, chatbot.Sources{
+		Stock:     stockSvc,
+		Movements: movementSvc,
+		Dashboard: dashboardSvc,
+		Requests:  requestService,
+	}, usageSvc, config.LoadChatbotConfig().HistoryTokenBudget)
+	handleChatBot := &handlers.BedbrockHandler{Db: db, LLM: llmService, Prompts: promptRegistry, Chat: chatService, Usage: usageSvc}
+	handleChatSession := &handlers.ChatSessionHandler{Service: chatService}
+	habdleDashboard := &handlers.DashboardHandler{Db: dbStarts, Service: dashboardSvc}
 	movementHandler := &handlers.MovementHandler{Service: movementSvc, Db: db}
 	etlService := Etl_service.EtlService{Db: dbStarts}
+	handleOutbox := &handlers.OutboxHandler{Outbox: outbox}
+	handlePrompt := &handlers.PromptHandler{Registry: promptRegistry}
+	handleUsage := &handlers.UsageHandler{Service: usageSvc}
 
-	configListener(etlService, requestService, mqConfig)
+	configListener(etlService, requestService, eventService, mqConfig)
 	initHealthRoutes(r, h)
 
 	initRequestRoutes(r, handleRequest)
 	initStockRoutes(r, handleStock)
 	initMovementRoutes(r, movementHandler)
-	initChatRoutes(r, handleChatBot)
+	initChatRoutes(r, handleChatBot, handleChatSession)
 	initDashboardRoutes(r, habdleDashboard)
+	initAdminRoutes(r, handleOutbox, handlePrompt, handleUsage)
+	initUsageRoutes(r, handleUsage)
 
 	initTestGateway(r, *h)
 
@@ -89,9 +127,32 @@ func initDashboardRoutes(r *chi.Mux, dashboard *handlers.DashboardHandler) {
 
 }
 
-func initChatRoutes(r *chi.Mux, bot *handlers.BedbrockHandler) {
+func initAdminRoutes(r *chi.Mux, outbox *handlers.OutboxHandler, prompts *handlers.PromptHandler, usage *handlers.UsageHandler) {
+	r.Route(AdminPath, func(r chi.Router) {
+		r.Get("/outbox/stuck", outbox.ListStuck)
+		r.Get("/prompts", prompts.List)
+		r.Post("/prompts", prompts.Create)
+		r.Post("/prompts/{id}/activate", prompts.Activate)
+		r.Put("/usage/quotas/{id}", usage.SetQuota)
+	})
+}
+
+func initUsageRoutes(r *chi.Mux, usage *handlers.UsageHandler) {
+	r.Route(UsagePath, func(r chi.Router) {
+		r.Get("/", usage.Report)
+	})
+}
+
+func initChatRoutes(r *chi.Mux, bot *handlers.BedbrockHandler, sessions *handlers.ChatSessionHandler) {
 	r.Route(ChatBot, func(r chi.Router) {
 		r.Get("/", bot.ConsultaProductos)
+		r.Get("/catalog", bot.ConsultaCatalogo)
+		r.Get("/sessions", sessions.List)
+		r.Post("/sessions", sessions.Create)
+		r.Get("/sessions/{id}", sessions.Get)
+		r.Delete("/sessions/{id}", sessions.Delete)
+		r.Post("/sessions/{id}/messages", sessions.SendMessage)
+		r.Post("/sessions/{id}/messages/stream", sessions.SendMessageStream)
 	})
 }
 
@@ -106,7 +167,14 @@ func initStockRoutes(r *chi.Mux, requestService *handlers.StockHandler) {
 
 	r.Route(APIBasePath, func(r chi.Router) {
 		r.Get("/", requestService.List)
+		r.Post("/", requestService.Create)
 		r.Get("/{id}", requestService.Get)
+		r.Patch("/{id}", requestService.Update)
+		r.Post("/{id}/archive", requestService.Archive)
+		r.Get("/{id}/skus", requestService.Skus)
+		r.Post("/{id}/skus", requestService.CreateSku)
+		r.Put("/{id}/skus/{skuId}", requestService.UpdateSku)
+		r.Delete("/{id}/skus/{skuId}", requestService.DeleteSku)
 	})
 
 }
@@ -134,7 +202,17 @@ func initRequestRoutes(r *chi.Mux, requestService *handlers.RequestHandler) {
 	r.Route(RequestBasePath, func(r chi.Router) {
 		r.Get("/", requestService.List)
 		r.Post("/", requestService.Create)
+		r.Post("/manual", requestService.CreateManual)
+		r.Get("/column-mapping", requestService.ColumnMapping)
+		r.Put("/column-mapping", requestService.SaveColumnMapping)
+		r.Get("/sku-aliases", requestService.SkuAliases)
+		r.Post("/sku-aliases", requestService.SaveSkuAlias)
+		r.Delete("/sku-aliases/{id}", requestService.DeleteSkuAlias)
 		r.Get("/{id}", requestService.Get)
+		r.Get("/{id}/history", requestService.History)
+		r.Post("/{id}/cancel", requestService.Cancel)
+		r.Post("/{id}/reprocess", requestService.Reprocess)
+		r.Get("/{id}/attempts", requestService.Attempts)
 		r.Patch("/", requestService.Update)
 	})
 }
@@ -160,37 +238,125 @@ func CheckNATGateway() bool {
 	return false
 }
 
-func configListener(etlService Etl_service.EtlService, requestService request.RequestService, mqCfg config.MQConfig) {
-	go listenRequestQueue(requestService, mqCfg)
-	go listenEtlQueue(etlService, mqCfg)
+func configListener(etlService Etl_service.EtlService, requestService request.RequestService, publisher *eventservice.MQPublisher, mqCfg config.MQConfig) {
+	if err := declareRetryQueues(mqCfg, requestQueue, etlProductQueue, etlAdjustQueue, etlCatalogQueue); err != nil {
+		log.Printf("❌ MQ topología: %v", err)
+	}
+
+	go listenRequestQueue(requestService, publisher, mqCfg)
+	go sweepStalledRequests(context.Background(), requestService)
+	go listenEtlQueue(etlService, publisher, mqCfg)
+	go listenEtlAdjustQueue(etlService, publisher, mqCfg)
+	go listenEtlCatalogQueue(etlService, publisher, mqCfg)
+}
+
+// El ETL reintenta cada etlRetryDelay: un ajuste puede llegar antes que la fila de hechos que corrige
+// y el outbox reintenta su publicación con backoff de hasta 5 minutos
+const (
+	etlRetryDelay   = 30 * time.Second
+	etlMaxAttempts  = 20
+	queueSuffix     = ".queue"
+	parkPublishWait = 5 * time.Second
+)
+
+var (
+	etlProductQueue = eventservice.RetryQueue{Name: eventservice.EtlProduct + queueSuffix, RoutingKey: eventservice.EtlProduct, Delay: etlRetryDelay, MaxAttempts: etlMaxAttempts}
+	etlAdjustQueue  = eventservice.RetryQueue{Name: eventservice.EtlAdjust + queueSuffix, RoutingKey: eventservice.EtlAdjust, Delay: etlRetryDelay, MaxAttempts: etlMaxAttempts}
+	etlCatalogQueue = eventservice.RetryQueue{Name: eventservice.EtlCatalog + queueSuffix, RoutingKey: eventservice.EtlCatalog, Delay: etlRetryDelay, MaxAttempts: etlMaxAttempts}
+)
+
+// legacyQueues son las colas que se reemplazaron por colas durables con reintento. Siguen enlazadas al exchange,
+// así que se borran al arrancar si ningún consumidor (una instancia anterior) las está usando.
+var legacyQueues = []string{"service.queue", eventservice.EtlProduct, eventservice.EtlAdjust, eventservice.EtlCatalog}
+
+// declareRetryQueues declara el exchange y las colas de reintento y de fallidos; las colas principales
+// las declaran sus consumidores con los argumentos de la cola de reintento
+func declareRetryQueues(mqCfg config.MQConfig, queues ...eventservice.RetryQueue) error {
+	conn, err := config.RabbitDial(mqCfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := eventservice.EnsureTopology(ch); err != nil {
+		return err
+	}
+	for _, queue := range queues {
+		if err := queue.Declare(ch); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range legacyQueues {
+		// un error de AMQP cierra el canal, así que cada borrado usa uno propio
+		legacy, err := conn.Channel()
+		if err != nil {
+			return err
+		}
+		if _, err := legacy.QueueDelete(name, true, false, false); err != nil {
+			log.Printf("⚠️ cola antigua %s no eliminada: %v", name, err)
+		}
+		legacy.Close()
+	}
+	return nil
+}
+
+// retryAction rechaza la entrega fallida para que vuelva tras el TTL de la cola de reintento
+// o, si agotó sus intentos, la deja en la cola de fallidos
+func retryAction(publisher *eventservice.MQPublisher, queue eventservice.RetryQueue, d rabbitmq.Delivery) rabbitmq.Action {
+	if !queue.Exhausted(d.Delivery) {
+		return rabbitmq.NackDiscard
+	}
+	return parkAction(publisher, queue, d)
+}
+
+// parkAction deja la entrega en la cola de fallidos; si no se puede, vuelve a la de reintento
+func parkAction(publisher *eventservice.MQPublisher, queue eventservice.RetryQueue, d rabbitmq.Delivery) rabbitmq.Action {
+	ctx, cancel := context.WithTimeout(context.Background(), parkPublishWait)
+	defer cancel()
+
+	if err := publisher.Park(ctx, queue, d); err != nil {
+		log.Printf("❌ no se pudo dejar el mensaje %s en %s: %v", d.MessageId, queue.FailedName(), err)
+		return rabbitmq.NackDiscard
+	}
+	log.Printf("⚠️ mensaje %s movido a %s tras %d intento(s)", d.MessageId, queue.FailedName(), queue.Failures(d.Delivery)+1)
+	return rabbitmq.Ack
 }
 
-func listenRequestQueue(requestService request.RequestService, mqCfg config.MQConfig) {
+// requestProcessTimeout acota la espera de Textract por entrega; si se cumple, el job queda guardado
+// en el documento y la reentrega, requestRetryDelay después, lo retoma
+const (
+	requestProcessTimeout = 2 * time.Minute
+	requestRetryDelay     = 30 * time.Second
+	requestMaxAttempts    = 5
+	stalledSweepInterval  = 5 * time.Minute
+)
+
+var requestQueue = eventservice.RetryQueue{Name: eventservice.RequestTopic + queueSuffix, RoutingKey: eventservice.RequestTopic, Delay: requestRetryDelay, MaxAttempts: requestMaxAttempts}
+
+func listenRequestQueue(requestService request.RequestService, publisher *eventservice.MQPublisher, mqCfg config.MQConfig) {
 	conn, _, err := config.RabbitConn(mqCfg)
 	if err != nil {
 		log.Printf("MQ publisher error: %v", err)
 		return
 	}
 
-	const exchange = eventservice.ExchangeName
-	const routingKey = eventservice.RequestTopic
-	const queueName = "service.queue"
-
-	args := rabbitmq.WithConsumerOptionsQueueArgs(map[string]interface{}{
-		"x-dead-letter-exchange":    "events.failover",
-		"x-dead-letter-routing-key": "Request.process.failover",
-		"x-message-ttl":             2000,
-	})
-
+	queue := requestQueue
 	consumer, err := rabbitmq.NewConsumer(
-		conn, queueName,
+		conn, queue.Name,
 		rabbitmq.WithConsumerOptionsQueueDurable,
-		rabbitmq.WithConsumerOptionsExchangeName(exchange),
+		rabbitmq.WithConsumerOptionsQueueArgs(queue.QueueArgs()),
+		rabbitmq.WithConsumerOptionsExchangeName(eventservice.ExchangeName),
 		rabbitmq.WithConsumerOptionsExchangeKind("topic"),
-		rabbitmq.WithConsumerOptionsRoutingKey(routingKey),
+		rabbitmq.WithConsumerOptionsRoutingKey(queue.RoutingKey),
 		rabbitmq.WithConsumerOptionsQOSPrefetch(5),
 		rabbitmq.WithConsumerOptionsConcurrency(5),
-		args,
 	)
 	if err != nil {
 		log.Fatalf("❌ NewConsumer: %v", err)
@@ -199,13 +365,15 @@ func listenRequestQueue(requestService request.RequestService, mqCfg config.MQCo
 	err = consumer.Run(func(d rabbitmq.Delivery) rabbitmq.Action {
 		var evt eventservice.RequestProcessEvent
 		if err := json.Unmarshal(d.Body, &evt); err != nil {
-			return rabbitmq.NackDiscard
+			log.Printf("❌ evento de solicitud inválido: %v", err)
+			return parkAction(publisher, queue, d)
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), requestProcessTimeout)
 		defer cancel()
 
-		if err := requestService.ProcessCtx(ctx, evt.RequestID, evt.ClientAccountId, evt.TypeIngress); err != nil {
-			return rabbitmq.NackRequeue
+		if err := requestService.ProcessCtx(ctx, evt); err != nil {
+			log.Printf("❌ procesando solicitud %s: %v", evt.RequestID, err)
+			return retryAction(publisher, queue, d)
 		}
 		return rabbitmq.Ack
 	})
@@ -214,26 +382,37 @@ func listenRequestQueue(requestService request.RequestService, mqCfg config.MQCo
 	}
 }
 
-func listenEtlQueue(etlService Etl_service.EtlService, mqCfg config.MQConfig) {
+// sweepStalledRequests vuelve a encolar al arrancar, y luego cada stalledSweepInterval, las solicitudes
+// con un job de Textract iniciado cuyo mensaje se perdió
+func sweepStalledRequests(ctx context.Context, requestService request.RequestService) {
+	ticker := time.NewTicker(stalledSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := requestService.RequeueStalled(ctx, requestMaxAttempts)
+		if err != nil {
+			log.Printf("❌ barrido de solicitudes detenidas: %v", err)
+		} else if n > 0 {
+			log.Printf("✅ %d solicitud(es) detenida(s) vuelven a la cola", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func listenEtlQueue(etlService Etl_service.EtlService, publisher *eventservice.MQPublisher, mqCfg config.MQConfig) {
 	conn, _, err := config.RabbitConn(mqCfg)
 	if err != nil {
 		log.Printf("MQ publisher error: %v", err)
 		return
 	}
 
-	const exchange = eventservice.ExchangeName
-	const routingKey = eventservice.EtlProduct // p. ej. "etl.product.created"
-	const queueName = routingKey
-
-	consumer, err := rabbitmq.NewConsumer(
-		conn, queueName,
-		rabbitmq.WithConsumerOptionsExchangeName(exchange),
-		rabbitmq.WithConsumerOptionsExchangeKind("topic"),
-		rabbitmq.WithConsumerOptionsRoutingKey(routingKey),
-		rabbitmq.WithConsumerOptionsQOSPrefetch(1),
-		rabbitmq.WithConsumerOptionsConcurrency(1),
-		rabbitmq.WithConsumerOptionsConsumerName("etl-product-consumer"),
-	)
+	queue := etlProductQueue
+	consumer, err := newEtlConsumer(conn, queue, "etl-product-consumer")
 	if err != nil {
 		log.Fatalf("❌ NewConsumer: %v", err)
 	}
@@ -242,10 +421,13 @@ func listenEtlQueue(etlService Etl_service.EtlService, mqCfg config.MQConfig) {
 		var evt eventservice.ProductEvent
 		if err := json.Unmarshal(d.Body, &evt); err != nil {
 			log.Printf("❌ ETL payload inválido: %v", err)
-			return rabbitmq.NackDiscard
+			return parkAction(publisher, queue, d)
 		}
 
-		etlService.StartETLConsumer(evt)
+		if err := etlService.StartETLConsumer(evt); err != nil {
+			log.Printf("❌ ETL product del movimiento %s: %v", evt.MovimientoID, err)
+			return retryAction(publisher, queue, d)
+		}
 
 		log.Printf("✅ ETL product procesado correctamente: %s", evt.ProductoID)
 		return rabbitmq.Ack
@@ -254,3 +436,84 @@ func listenEtlQueue(etlService Etl_service.EtlService, mqCfg config.MQConfig) {
 		log.Fatalf("❌ Error en consumer.Run (ETL): %v", err)
 	}
 }
+
+func listenEtlAdjustQueue(etlService Etl_service.EtlService, publisher *eventservice.MQPublisher, mqCfg config.MQConfig) {
+	conn, _, err := config.RabbitConn(mqCfg)
+	if err != nil {
+		log.Printf("MQ publisher error: %v", err)
+		return
+	}
+
+	queue := etlAdjustQueue
+	consumer, err := newEtlConsumer(conn, queue, "etl-adjust-consumer")
+	if err != nil {
+		log.Fatalf("❌ NewConsumer: %v", err)
+	}
+
+	err = consumer.Run(func(d rabbitmq.Delivery) rabbitmq.Action {
+		var evt eventservice.ProductAdjustEvent
+		if err := json.Unmarshal(d.Body, &evt); err != nil {
+			log.Printf("❌ ETL ajuste inválido: %v", err)
+			return parkAction(publisher, queue, d)
+		}
+
+		if err := etlService.ApplyAdjustment(evt); err != nil {
+			log.Printf("❌ ETL ajuste del movimiento %s: %v", evt.MovimientoID, err)
+			return retryAction(publisher, queue, d)
+		}
+
+		log.Printf("✅ ETL ajuste aplicado: %s", evt.MovimientoID)
+		return rabbitmq.Ack
+	})
+	if err != nil {
+		log.Fatalf("❌ Error en consumer.Run (ETL ajuste): %v", err)
+	}
+}
+
+func listenEtlCatalogQueue(etlService Etl_service.EtlService, publisher *eventservice.MQPublisher, mqCfg config.MQConfig) {
+	conn, _, err := config.RabbitConn(mqCfg)
+	if err != nil {
+		log.Printf("MQ publisher error: %v", err)
+		return
+	}
+
+	queue := etlCatalogQueue
+	consumer, err := newEtlConsumer(conn, queue, "etl-catalog-consumer")
+	if err != nil {
+		log.Fatalf("❌ NewConsumer: %v", err)
+	}
+
+	err = consumer.Run(func(d rabbitmq.Delivery) rabbitmq.Action {
+		var evt eventservice.ProductCatalogEvent
+		if err := json.Unmarshal(d.Body, &evt); err != nil {
+			log.Printf("❌ ETL catálogo inválido: %v", err)
+			return parkAction(publisher, queue, d)
+		}
+
+		if err := etlService.ApplyCatalog(evt); err != nil {
+			log.Printf("❌ ETL catálogo del producto %s: %v", evt.ProductoID, err)
+			return retryAction(publisher, queue, d)
+		}
+
+		log.Printf("✅ ETL catálogo aplicado: %s", evt.ProductoID)
+		return rabbitmq.Ack
+	})
+	if err != nil {
+		log.Fatalf("❌ Error en consumer.Run (ETL catálogo): %v", err)
+	}
+}
+
+// newEtlConsumer consume de a un mensaje una cola durable del ETL con reintento diferido
+func newEtlConsumer(conn *rabbitmq.Conn, queue eventservice.RetryQueue, name string) (*rabbitmq.Consumer, error) {
+	return rabbitmq.NewConsumer(
+		conn, queue.Name,
+		rabbitmq.WithConsumerOptionsQueueDurable,
+		rabbitmq.WithConsumerOptionsQueueArgs(queue.QueueArgs()),
+		rabbitmq.WithConsumerOptionsExchangeName(eventservice.ExchangeName),
+		rabbitmq.WithConsumerOptionsExchangeKind("topic"),
+		rabbitmq.WithConsumerOptionsRoutingKey(queue.RoutingKey),
+		rabbitmq.WithConsumerOptionsQOSPrefetch(1),
+		rabbitmq.WithConsumerOptionsConcurrency(1),
+		rabbitmq.WithConsumerOptionsConsumerName(name),
+	)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChatSession es una conversación del chatbot de un usuario dentro de una cuenta cliente.
+// ProductID acota la conversación a un producto; nil es una conversación general.
+type ChatSession struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ClientAccountID uuid.UUID  `gorm:"column:client_account_id;type:uuid;not null"`
+	UserID          string     `gorm:"column:user_id;type:varchar;not null"`
+	Title           string     `gorm:"column:title;type:varchar;not null"`
+	ProductID       *uuid.UUID `gorm:"column:product_id;type:uuid;default:null"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (ChatSession) TableName() string { return "chat_session" }
+
+// ChatMessage es un turno de la conversación; los de assistant guardan el modelo y el consumo de la llamada
+type ChatMessage struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	SessionID    uuid.UUID `gorm:"column:session_id;type:uuid;not null"`
+	Role         string    `gorm:"column:role;type:varchar;not null"`
+	Content      string    `gorm:"column:content;type:text;not null"`
+	Model        string    `gorm:"column:model;type:varchar;default:null"`
+	InputTokens  int       `gorm:"column:input_tokens;not null"`
+	OutputTokens int       `gorm:"column:output_tokens;not null"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (ChatMessage) TableName() string { return "chat_message" }
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ColumnMapping guarda, por cuenta cliente, qué columnas de una planilla (CSV/XLSX) traen nombre, cantidad y SKU.
+type ColumnMapping struct {
+	ID              uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ClientAccountID uuid.UUID `gorm:"column:client_account_id;type:uuid;not null"`
+	NameColumn      string    `gorm:"column:name_column;type:varchar;default:null"`
+	QuantityColumn  string    `gorm:"column:quantity_column;type:varchar;not null"`
+	SkuColumn       string    `gorm:"column:sku_column;type:varchar;default:null"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (ColumnMapping) TableName() string { return "column_mapping" }
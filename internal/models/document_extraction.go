@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentExtraction registra cada llamada al LLM al extraer un documento, incluidas las de reparación,
+// con la salida cruda y los errores de validación.
+type DocumentExtraction struct {
+	ID               uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	DocumentID       uuid.UUID `gorm:"column:document_id;type:uuid;not null"`
+	RequestAttemptID uuid.UUID `gorm:"column:request_attempt_id;type:uuid;not null"`
+	Attempt          int       `gorm:"column:attempt;not null"`
+	Model            string    `gorm:"column:model;type:varchar;not null"`
+	Output           string    `gorm:"column:output;type:text"`
+	Errors           string    `gorm:"column:errors;type:jsonb;default:null"`
+	Valid            bool      `gorm:"column:valid;not null"`
+	InputTokens      int       `gorm:"column:input_tokens;not null"`
+	OutputTokens     int       `gorm:"column:output_tokens;not null"`
+	CreatedAt        time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (DocumentExtraction) TableName() string { return "document_extraction" }
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ExtractionCache guarda resultados de Textract y del LLM por hash del contenido, para no pagarlos dos veces
+type ExtractionCache struct {
+	Key       string    `gorm:"column:key;type:varchar;primaryKey"`
+	Payload   string    `gorm:"column:payload;type:jsonb;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (ExtractionCache) TableName() string { return "extraction_cache" }
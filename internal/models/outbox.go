@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending"
+	OutboxStatusSent    OutboxStatus = "sent"
+)
+
+// OutboxEvent es un evento pendiente de publicar, escrito en la misma transacción que el cambio que lo origina.
+type OutboxEvent struct {
+	ID            uuid.UUID    `gorm:"type:uuid;primaryKey"`
+	RoutingKey    string       `gorm:"column:routing_key;type:varchar;not null"`
+	Payload       string       `gorm:"column:payload;type:jsonb;not null"`
+	Headers       string       `gorm:"column:headers;type:jsonb"`
+	Status        OutboxStatus `gorm:"column:status;type:varchar(20);not null"`
+	Attempts      int          `gorm:"column:attempts;not null"`
+	LastError     string       `gorm:"column:last_error;type:varchar;default:null"`
+	NextAttemptAt time.Time    `gorm:"column:next_attempt_at"`
+	CreatedAt     time.Time    `gorm:"column:created_at;autoCreateTime"`
+	SentAt        *time.Time   `gorm:"column:sent_at"`
+}
+
+func (OutboxEvent) TableName() string { return "outbox_event" }
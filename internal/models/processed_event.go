@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProcessedEvent registra los eventos de procesamiento ya aplicados para descartar reentregas.
+type ProcessedEvent struct {
+	EventID     string    `gorm:"column:event_id;type:varchar;primaryKey"`
+	RequestID   uuid.UUID `gorm:"column:request_id;type:uuid;primaryKey"`
+	ProcessedAt time.Time `gorm:"column:processed_at;autoCreateTime"`
+}
+
+func (ProcessedEvent) TableName() string { return "processed_event" }
@@ -6,6 +6,13 @@ import (
 	"github.com/google/uuid"
 )
 
+// Estados de un producto; los archivados no se ofrecen al resolver líneas de nuevas solicitudes
+const (
+	ProductStatusActive   = "active"
+	ProductStatusInactive = "inactive"
+	ProductStatusArchived = "archived"
+)
+
 type Product struct {
 	ID            uuid.UUID `gorm:"column:id;type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	ReferencialID uuid.UUID `gorm:"type:uuid;not null" json:"referencial_id"`
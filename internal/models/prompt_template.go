@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PromptTemplate es una versión de un prompt; ClientAccountID nil es la versión global del caso de uso.
+type PromptTemplate struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UseCase         string     `gorm:"column:use_case;type:varchar;not null"`
+	Version         string     `gorm:"column:version;type:varchar;not null"`
+	ClientAccountID *uuid.UUID `gorm:"column:client_account_id;type:uuid;default:null"`
+	Template        string     `gorm:"column:template;type:text;not null"`
+	Active          bool       `gorm:"column:active;not null"`
+	CreatedBy       string     `gorm:"column:created_by;type:varchar;default:null"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime"`
+	ActivatedAt     *time.Time `gorm:"column:activated_at;default:null"`
+}
+
+func (PromptTemplate) TableName() string { return "prompt_template" }
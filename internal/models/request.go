@@ -17,13 +17,30 @@ type Request struct {
 }
 
 type Documents struct {
-	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	S3Path     string    `gorm:"column:s3_path;type:varchar"`
-	RequestID  uuid.UUID `gorm:"column:request_id;type:uuid"`
-	TextractId string    `gorm:"column:textract_id;type:varchar;default:null"`
-	bedrockId  string    `gorm:"column:bedrock_id;type:varchar;default:null"`
-	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt  time.Time `gorm:"column:update_at;autoUpdateTime"`
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	S3Path      string    `gorm:"column:s3_path;type:varchar"`
+	FileName    string    `gorm:"column:file_name;type:varchar;default:null"`
+	ContentType string    `gorm:"column:content_type;type:varchar;default:null"`
+	ContentHash string    `gorm:"column:content_hash;type:varchar(64);default:null"`
+	RequestID   uuid.UUID `gorm:"column:request_id;type:uuid"`
+	TextractId  string    `gorm:"column:textract_id;type:varchar;default:null"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"column:update_at;autoUpdateTime"`
+
+	// cabecera de la factura leída por Textract (FORMS)
+	SupplierName  string     `gorm:"column:supplier_name;type:varchar;default:null"`
+	SupplierTaxID string     `gorm:"column:supplier_tax_id;type:varchar;default:null"`
+	InvoiceFolio  string     `gorm:"column:invoice_folio;type:varchar;default:null"`
+	IssueDate     *time.Time `gorm:"column:issue_date;type:date;default:null"`
+	Currency      string     `gorm:"column:currency;type:varchar(10);default:null"`
+	NetAmount     *float64   `gorm:"column:net_amount;type:numeric(18,2);default:null"`
+	TaxAmount     *float64   `gorm:"column:tax_amount;type:numeric(18,2);default:null"`
+	TotalAmount   *float64   `gorm:"column:total_amount;type:numeric(18,2);default:null"`
+
+	// trazabilidad de la última extracción con LLM
+	PromptVersion string `gorm:"column:prompt_version;type:varchar;default:null"`
+	ModelID       string `gorm:"column:model_id;type:varchar;default:null"`
+	RawOutput     string `gorm:"column:raw_output;type:text;default:null"`
 }
 
 type RequestPerProduct struct {
@@ -76,6 +93,37 @@ func (s RequestStatus) IsValid() bool {
 	return false
 }
 
+// requestTransitions es la única fuente de verdad de los cambios de estado permitidos
+var requestTransitions = map[RequestStatus][]RequestStatus{
+	RequestCreated:        {RequestStatusPending, RequestStatusRejected},
+	RequestStatusPending:  {RequestStatusApproved, RequestStatusRejected, RequestStatusCancelled},
+	RequestStatusApproved: {RequestStatusCancelled},
+	RequestStatusRejected: {RequestCreated}, // reprocesamiento
+}
+
+func (s RequestStatus) CanTransitionTo(next RequestStatus) bool {
+	for _, allowed := range requestTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type RequestStatusHistory struct {
+	ID         uuid.UUID     `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	RequestID  uuid.UUID     `gorm:"column:request_id;type:uuid;not null"`
+	FromStatus RequestStatus `gorm:"column:from_status;type:varchar(50);default:null"`
+	ToStatus   RequestStatus `gorm:"column:to_status;type:varchar(50);not null"`
+	ChangedBy  string        `gorm:"column:changed_by;type:varchar;not null"`
+	Reason     string        `gorm:"column:reason;type:varchar;default:null"`
+	CreatedAt  time.Time     `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (RequestStatusHistory) TableName() string {
+	return "request_status_history"
+}
+
 func (Request) TableName() string {
 	return "request"
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AttemptStatus string
+
+const (
+	AttemptRunning   AttemptStatus = "running"
+	AttemptSucceeded AttemptStatus = "succeeded"
+	AttemptRejected  AttemptStatus = "rejected"
+	AttemptFailed    AttemptStatus = "failed"
+)
+
+// RequestAttempt guarda el resultado de cada ejecución del procesamiento de una solicitud.
+type RequestAttempt struct {
+	ID              uuid.UUID     `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	RequestID       uuid.UUID     `gorm:"column:request_id;type:uuid;not null"`
+	Attempt         int           `gorm:"column:attempt;not null"`
+	Model           string        `gorm:"column:model;type:varchar"`
+	PromptVersion   string        `gorm:"column:prompt_version;type:varchar"`
+	Status          AttemptStatus `gorm:"column:status;type:varchar(20);not null"`
+	Lines           int           `gorm:"column:lines;not null"`
+	Error           string        `gorm:"column:error;type:varchar;default:null"`
+	CreatedProducts string        `gorm:"column:created_products;type:jsonb;default:null"` // ids de productos creados por el intento
+	StartedAt       time.Time     `gorm:"column:started_at"`
+	FinishedAt      *time.Time    `gorm:"column:finished_at"`
+}
+
+func (RequestAttempt) TableName() string { return "request_attempt" }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestLine guarda la línea extraída que originó cada movimiento, de qué documentos salió
+// y la confianza con que se leyó.
+type RequestLine struct {
+	ID              uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	RequestID       uuid.UUID `gorm:"column:request_id;type:uuid;not null"`
+	MovementID      uuid.UUID `gorm:"column:movement_id;type:uuid;not null"`
+	ProductID       uuid.UUID `gorm:"column:product_id;type:uuid;not null"`
+	Name            string    `gorm:"column:name;type:varchar;not null"`
+	Count           int       `gorm:"column:count;not null"`
+	Skus            string    `gorm:"column:skus;type:jsonb;default:null"`
+	SourceDocuments string    `gorm:"column:source_documents;type:jsonb;default:null"`
+	Confidence      *float64  `gorm:"column:confidence;type:numeric(4,3);default:null"`
+	ReasonCodes     string    `gorm:"column:reason_codes;type:jsonb;default:null"`
+	NeedsReview     bool      `gorm:"column:needs_review;not null;default:false"`
+	// Candidates son los productos de la cuenta que podrían corresponder a la línea, con su puntaje
+	Candidates string    `gorm:"column:candidates;type:jsonb;default:null"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (RequestLine) TableName() string { return "request_line" }
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SkuAlias es un código o descripción con que un proveedor nombra un producto de la cuenta cliente.
+// SupplierTaxID nil es un alias de la cuenta para cualquier proveedor.
+type SkuAlias struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ClientAccountID uuid.UUID  `gorm:"column:client_account_id;type:uuid;not null"`
+	SupplierTaxID   *string    `gorm:"column:supplier_tax_id;type:varchar;default:null"`
+	Alias           string     `gorm:"column:alias;type:varchar;not null"`
+	AliasNormalized string     `gorm:"column:alias_normalized;type:varchar;not null"`
+	ProductID       uuid.UUID  `gorm:"column:product_id;type:uuid;not null"`
+	SkuID           *uuid.UUID `gorm:"column:sku_id;type:uuid;default:null"`
+	CreatedBy       string     `gorm:"column:created_by;type:varchar;default:null"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (SkuAlias) TableName() string { return "sku_alias" }
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	UsageKindTextract = "textract"
+	UsageKindLLM      = "llm"
+)
+
+// UsageRecord es una llamada cobrable a AWS (un análisis de Textract o una llamada al LLM) de una cuenta cliente.
+// RequestID es nil para las llamadas que no vienen de una solicitud, como las del chatbot.
+type UsageRecord struct {
+	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ClientAccountID uuid.UUID  `gorm:"column:client_account_id;type:uuid;not null"`
+	RequestID       *uuid.UUID `gorm:"column:request_id;type:uuid;default:null"`
+	Kind            string     `gorm:"column:kind;type:varchar;not null"`
+	Operation       string     `gorm:"column:operation;type:varchar;not null"`
+	Model           string     `gorm:"column:model;type:varchar;default:null"`
+	InputTokens     int        `gorm:"column:input_tokens;not null"`
+	OutputTokens    int        `gorm:"column:output_tokens;not null"`
+	Pages           int        `gorm:"column:pages;not null"`
+	EstimatedCost   float64    `gorm:"column:estimated_cost;type:numeric(12,6);not null"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (UsageRecord) TableName() string { return "usage_record" }
+
+// UsageQuota son los límites mensuales de una cuenta cliente; 0 es sin límite
+type UsageQuota struct {
+	ClientAccountID uuid.UUID `gorm:"column:client_account_id;type:uuid;primaryKey"`
+	MonthlyPages    int       `gorm:"column:monthly_pages;not null"`
+	MonthlyTokens   int       `gorm:"column:monthly_tokens;not null"`
+	MonthlyCost     float64   `gorm:"column:monthly_cost;type:numeric(12,2);not null"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (UsageQuota) TableName() string { return "usage_quota" }
@@ -1,6 +1,8 @@
 package Etl_service
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -9,83 +11,225 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrFactNotLoaded indica que el ajuste llegó antes que la fila de hechos del movimiento, que viaja por otra cola;
+// el ajuste se deshace completo y se reintenta
+var ErrFactNotLoaded = errors.New("el movimiento todavía no está en fact_product_movement")
+
 type EtlService struct {
 	Db *gorm.DB
 }
 
-func (e EtlService) StartETLConsumer(evt eventservice.ProductEvent) {
+// StartETLConsumer carga el movimiento en fact_product_movement y su cantidad en el stock de dim_producto.
+// Una reentrega del mismo movimiento choca con el índice único de movimiento_uuid y no vuelve a sumar.
+func (e EtlService) StartETLConsumer(evt eventservice.ProductEvent) error {
+	return e.Db.Transaction(func(tx *gorm.DB) error {
+
+		// --- TRANSFORMACIÓN + CARGA ---
+		var clienteID int
+		if err := tx.Raw("SELECT id FROM dim_cliente WHERE cliente_uuid = ?", evt.ClienteID).Scan(&clienteID).Error; err != nil {
+			return err
+		}
+
+		productoID, err := getProductId(tx, evt)
+		if err != nil {
+			return err
+		}
+
+		fechaKey, err := getFechaKey(tx, evt)
+		if err != nil {
+			return err
+		}
+
+		solicitudID, err := getSolicitudId(tx, evt)
+		if err != nil {
+			return err
+		}
+
+		var typeMovement int
+		if evt.Signo == 1 {
+			typeMovement = 7 // entrada
+		} else {
+			typeMovement = 8 // salida
+		}
+
+		// Insertar fila en la tabla de hechos
+		result := tx.Exec(`
+			INSERT INTO fact_product_movement (movimiento_uuid, producto_id, cliente_id, cantidad, signo, tipo_movimiento_id, fecha_key, solicitud_id, created_at)
+			VALUES (NULLIF(?, '')::uuid, ?, ?, ?, ?, ?, ?, ?, NOW())
+			ON CONFLICT (movimiento_uuid) WHERE movimiento_uuid IS NOT NULL DO NOTHING
+		`, evt.MovimientoID, productoID, clienteID, evt.Cantidad, evt.Signo, typeMovement, fechaKey, solicitudID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			log.Printf("Movimiento %s ya cargado en fact_product_movement, se descarta", evt.MovimientoID)
+			return nil
+		}
 
-	// --- TRANSFORMACIÓN + CARGA ---
-	var clienteID int
-	e.Db.Raw("SELECT id FROM dim_cliente WHERE cliente_uuid = ?", evt.ClienteID).Scan(&clienteID)
+		return tx.Exec("UPDATE dim_producto SET stock = stock + ? WHERE id = ?", evt.Cantidad*evt.Signo, productoID).Error
+	})
+}
 
-	productoID := e.getProductId(evt)
+// ApplyAdjustment refleja en el esquema estrella la corrección de un movimiento confirmado.
+// El ajuste suma deltas, así que cada mensaje del outbox se aplica una sola vez.
+func (e EtlService) ApplyAdjustment(evt eventservice.ProductAdjustEvent) error {
+	return e.Db.Transaction(func(tx *gorm.DB) error {
+		duplicate, err := markApplied(tx, evt.BaseEvent, eventservice.EtlAdjust)
+		if err != nil || duplicate {
+			return err
+		}
+
+		err = tx.Exec("UPDATE dim_producto SET stock = stock + ? WHERE producto_uuid = ?", evt.DeltaStock, evt.ProductoID).Error
+		if err != nil {
+			return err
+		}
 
-	fechaKey := e.getFechaKey(evt)
+		if evt.ProductoDestinoID != "" {
+			if err := moveFact(tx, evt); err != nil {
+				return err
+			}
+		}
 
-	solicitudID := e.getSolicitudId(evt, clienteID)
+		result := tx.Exec(`
+			UPDATE fact_product_movement
+			SET cantidad = ?, updated_at = NOW()
+			WHERE movimiento_uuid = ?
+		`, evt.Cantidad, evt.MovimientoID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrFactNotLoaded, evt.MovimientoID)
+		}
+		return nil
+	})
+}
 
-	var typeMovement int
-	if evt.Signo == 1 {
-		typeMovement = 7 // entrada
-	} else {
-		typeMovement = 8 // salida
+// markApplied registra el mensaje del outbox en etl_processed_message; duplicate indica que una entrega anterior ya lo aplicó.
+// El registro es parte de la transacción del cambio, así que un fallo lo deshace junto con él.
+func markApplied(tx *gorm.DB, evt eventservice.BaseEvent, routingKey string) (duplicate bool, err error) {
+	if evt.EventID == "" {
+		return false, nil
+	}
+	result := tx.Exec(`
+		INSERT INTO etl_processed_message (message_id, routing_key, processed_at)
+		VALUES (?, ?, NOW())
+		ON CONFLICT (message_id) DO NOTHING
+	`, evt.EventID, routingKey)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("Mensaje %s ya aplicado en el esquema estrella, se descarta", evt.EventID)
+		return true, nil
+	}
+	return false, nil
+}
+
+// moveFact pasa la fila de hechos del movimiento al producto destino, creándolo en la dimensión si no está
+func moveFact(tx *gorm.DB, evt eventservice.ProductAdjustEvent) error {
+	err := tx.Exec(`
+		INSERT INTO dim_producto (producto_uuid, nombre, creado_en, stock, status, cliente_uuid)
+		SELECT ?, ?, NOW(), 0, 'activo', ?
+		WHERE NOT EXISTS (SELECT 1 FROM dim_producto WHERE producto_uuid = ?)
+	`, evt.ProductoDestinoID, evt.NombreDestino, evt.ClienteID, evt.ProductoDestinoID).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec("UPDATE dim_producto SET stock = stock + ? WHERE producto_uuid = ?", evt.DeltaStockDestino, evt.ProductoDestinoID).Error
+	if err != nil {
+		return err
 	}
 
-	// Insertar fila en la tabla de hechos
-	e.Db.Exec(`
-      INSERT INTO fact_product_movement (producto_id, cliente_id, cantidad, signo, tipo_movimiento_id, fecha_key, solicitud_id, created_at)
-      VALUES (?, ?, ?, ?, ?, ?, ?, NOW())
-    `, productoID, clienteID, evt.Cantidad, evt.Signo, typeMovement, fechaKey, solicitudID)
+	result := tx.Exec(`
+		UPDATE fact_product_movement
+		SET producto_id = (SELECT id FROM dim_producto WHERE producto_uuid = ?), updated_at = NOW()
+		WHERE movimiento_uuid = ?
+	`, evt.ProductoDestinoID, evt.MovimientoID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrFactNotLoaded, evt.MovimientoID)
+	}
+	return nil
+}
 
+// ApplyCatalog refleja en dim_producto el alta o el cambio de nombre o estado de un producto del catálogo.
+// El stock no se toca: solo cambia con movimientos.
+func (e EtlService) ApplyCatalog(evt eventservice.ProductCatalogEvent) error {
+	return e.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec("UPDATE dim_producto SET nombre = ?, status = ? WHERE producto_uuid = ?",
+			evt.NombreProducto, dimStatus(evt.Status), evt.ProductoID)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		return tx.Exec(`
+			INSERT INTO dim_producto (producto_uuid, nombre, creado_en, stock, status, cliente_uuid)
+			VALUES (?, ?, NOW(), 0, ?, ?)
+		`, evt.ProductoID, evt.NombreProducto, dimStatus(evt.Status), evt.ClienteID).Error
+	})
 }
 
-func (e EtlService) getSolicitudId(evt eventservice.ProductEvent, clienteID int) int {
+// dimStatus traduce el estado del producto al que usa la dimensión
+func dimStatus(status string) string {
+	switch status {
+	case "inactive":
+		return "inactivo"
+	case "archived":
+		return "archivado"
+	default:
+		return "activo"
+	}
+}
+
+func getSolicitudId(tx *gorm.DB, evt eventservice.ProductEvent) (int, error) {
 	var solicitudID int
-	if evt.SolicitudId != "" {
-		e.Db.Raw("SELECT id FROM dim_solicitud WHERE solicitud_uuid = ?", evt.SolicitudId).Scan(&solicitudID)
-		if solicitudID == 0 {
-			// Si no existe, creamos la nueva solicitud con los datos disponibles
-			clienteUUID, _ := uuid.Parse(evt.ClienteID)
-
-			err := e.Db.Exec(`
-				INSERT INTO dim_solicitud (solicitud_uuid, cliente_uuid, status, creado_en)
-				VALUES (?, ?, ?, NOW())
-			`, evt.SolicitudId, clienteUUID, evt.StatusSolicitud).Error
-			if err != nil {
-				log.Println(err)
-			}
-			e.Db.Raw("SELECT id FROM dim_solicitud WHERE solicitud_uuid = ?", evt.SolicitudId).Scan(&solicitudID)
+	if evt.SolicitudId == "" {
+		return solicitudID, nil
+	}
+	if err := tx.Raw("SELECT id FROM dim_solicitud WHERE solicitud_uuid = ?", evt.SolicitudId).Scan(&solicitudID).Error; err != nil {
+		return 0, err
+	}
+	if solicitudID == 0 {
+		// Si no existe, creamos la nueva solicitud con los datos disponibles
+		clienteUUID, _ := uuid.Parse(evt.ClienteID)
+
+		err := tx.Raw(`
+			INSERT INTO dim_solicitud (solicitud_uuid, cliente_uuid, status, creado_en)
+			VALUES (?, ?, ?, NOW())
+			RETURNING id
+		`, evt.SolicitudId, clienteUUID, evt.StatusSolicitud).Scan(&solicitudID).Error
+		if err != nil {
+			return 0, err
 		}
 	}
-	return solicitudID
+	return solicitudID, nil
 }
 
-func (e EtlService) getProductId(evt eventservice.ProductEvent) int {
+// getProductId devuelve el producto en la dimensión, creándolo sin stock si no está; el stock lo suma el hecho
+func getProductId(tx *gorm.DB, evt eventservice.ProductEvent) (int, error) {
 	var productoID int
-	e.Db.Raw("SELECT id FROM dim_producto WHERE producto_uuid = ?", evt.ProductoID).Scan(&productoID)
+	if err := tx.Raw("SELECT id FROM dim_producto WHERE producto_uuid = ?", evt.ProductoID).Scan(&productoID).Error; err != nil {
+		return 0, err
+	}
 	if productoID == 0 {
 		// Si el producto no existe en la dimensión, lo insertamos
-		e.Db.Exec(`
-		INSERT INTO dim_producto (producto_uuid, nombre, creado_en, stock, status, cliente_uuid)
-		VALUES (?, ?, ?, ?, ?, ?)
-	  `, evt.ProductoID, evt.NombreProducto, time.Now(), evt.Cantidad*evt.Signo, "activo", evt.ClienteID)
-		// Obtenemos el ID del producto recién insertado
-		e.Db.Raw("SELECT id FROM dim_producto WHERE producto_uuid = ?", evt.ProductoID).Scan(&productoID)
-
-	} else {
-		// Si el producto ya existe, actualizamos su stock
-		e.Db.Exec(`
-		UPDATE dim_producto
-		SET stock = stock + ?
-		WHERE producto_uuid = ?
-	  `, evt.Cantidad*evt.Signo, evt.ProductoID)
-
+		err := tx.Raw(`
+			INSERT INTO dim_producto (producto_uuid, nombre, creado_en, stock, status, cliente_uuid)
+			VALUES (?, ?, ?, 0, ?, ?)
+			RETURNING id
+		`, evt.ProductoID, evt.NombreProducto, time.Now(), "activo", evt.ClienteID).Scan(&productoID).Error
+		if err != nil {
+			return 0, err
+		}
 	}
-	return productoID
+	return productoID, nil
 }
 
-func (e EtlService) getFechaKey(evt eventservice.ProductEvent) int {
+func getFechaKey(tx *gorm.DB, evt eventservice.ProductEvent) (int, error) {
 	fechaMovimiento := evt.Fecha
 	// Extraer partes
 	dia := fechaMovimiento.Day()
@@ -97,18 +241,11 @@ func (e EtlService) getFechaKey(evt eventservice.ProductEvent) int {
 	nombreDia := fechaMovimiento.Weekday().String()
 	fechaKey := anio*10000 + mes*100 + dia // Ej: 20251030
 
-	// 3️⃣ Buscar si la fecha ya está en dim_fecha
-	var existe bool
-	e.Db.Raw("SELECT EXISTS(SELECT 1 FROM dim_fecha WHERE fecha_key = ?)", fechaKey).Scan(&existe)
-	if !existe {
-		// Insertar si no existe
-		err := e.Db.Exec(`
-			INSERT INTO dim_fecha (fecha_key, fecha, dia, mes, anio, trimestre, dia_semana, nombre_dia, nombre_mes)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			fechaKey, fechaMovimiento, dia, mes, anio, trimestre, diaSemana, nombreDia, nombreMes).Error
-		if err != nil {
-			log.Println(err)
-		}
-	}
-	return fechaKey
+	// Insertar si no existe
+	err := tx.Exec(`
+		INSERT INTO dim_fecha (fecha_key, fecha, dia, mes, anio, trimestre, dia_semana, nombre_dia, nombre_mes)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM dim_fecha WHERE fecha_key = ?)`,
+		fechaKey, fechaMovimiento, dia, mes, anio, trimestre, diaSemana, nombreDia, nombreMes, fechaKey).Error
+	return fechaKey, err
 }
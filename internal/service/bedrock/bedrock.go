@@ -2,14 +2,12 @@ package bedrock
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/stock-ahora/api-stock/internal/config"
 )
 
 type ProductResponse struct {
@@ -18,141 +16,168 @@ type ProductResponse struct {
 	SKUs  []string `json:"skus"`
 }
 
+// Service arma los prompts de la aplicación sobre un Client; modelo, tokens y temperatura vienen de la configuración
 type Service struct {
-	client *bedrockruntime.Client
-	model  string
+	client         Client
+	model          string
+	maxTokens      int
+	temperature    float64
+	repairAttempts int
 }
 
-func NewBedrockService(ctx context.Context, model string, region string) *Service {
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
-	if err != nil {
-		log.Fatalf("unable to load SDK config, %v", err)
-		return nil
+func NewService(client Client, cfg config.LLMConfig) *Service {
+	model := cfg.Model
+	if model == "" {
+		model = NOVA_PRO_AWS
+	}
+	return &Service{
+		client:         client,
+		model:          model,
+		maxTokens:      cfg.MaxTokens,
+		temperature:    cfg.Temperature,
+		repairAttempts: cfg.RepairAttempts,
 	}
-	client := bedrockruntime.NewFromConfig(cfg)
-	return &Service{client: client, model: model}
 }
 
-func (b *Service) FormatProduct(ctx context.Context, input string, promptPremilinar string) (*[]ProductResponse, error) {
-	prompt := fmt.Sprintf(promptPremilinar, input)
-
-	body := fmt.Sprintf(`{
-      "messages": [
-        {
-          "role": "user",
-          "content": [
-            { "text": %q }
-          ]
-        }
-      ],
-      "inferenceConfig": {
-        "maxTokens": 712,
-        "temperature": 0
-      }
-    }`, prompt)
-
-	resp, err := b.client.InvokeModel(context.Background(), &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(NOVA_PRO_AWS),
-		ContentType: aws.String("application/json"),
-		Accept:      aws.String("application/json"),
-		Body:        []byte(body),
-	})
-	if err != nil {
-		log.Println("Error invoking Bedrock model:", err)
-		return nil, err
-	}
+// Model es el modelo por defecto de la configuración
+func (b *Service) Model() string {
+	return b.model
+}
 
-	var parsed map[string]interface{}
-	if err := json.Unmarshal(resp.Body, &parsed); err != nil {
-		return nil, err
+// WithModel devuelve una copia que invoca otro modelo (p. ej. el pedido al reprocesar); vacío mantiene el actual
+func (b *Service) WithModel(model string) *Service {
+	if model == "" {
+		return b
 	}
+	clone := *b
+	clone.model = model
+	return &clone
+}
 
-	resultBytes, _ := json.Marshal(parsed)
-	log.Println("Raw response:", string(resultBytes))
-
-	products, err := parseBedrockResponse(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	return &products, nil
+func (b *Service) complete(ctx context.Context, prompt string) (*Completion, error) {
+	return b.client.Complete(ctx, b.request(Message{Role: RoleUser, Text: prompt}))
 }
 
-type Response struct {
-	Output struct {
-		Message struct {
-			Content []struct {
-				Text string `json:"text"`
-			} `json:"content"`
-			Role string `json:"role"`
-		} `json:"message"`
-	} `json:"output"`
-	StopReason string                 `json:"stopReason"`
-	Usage      map[string]interface{} `json:"usage"`
+// Chat envía una conversación completa (turnos alternados empezando por el usuario) con un prompt de sistema.
+// Con tools el modelo puede responder con ToolCalls en vez de texto; ejecutarlas queda a cargo del llamador.
+func (b *Service) Chat(ctx context.Context, system string, messages []Message, tools ...Tool) (*Completion, error) {
+	req := b.request(messages...)
+	req.System = system
+	req.Tools = tools
+	return b.client.Complete(ctx, req)
 }
 
-func parseBedrockResponse(respBody []byte) ([]ProductResponse, error) {
-	// 1. Parsear la respuesta de Bedrock
-	var br Response
-	if err := json.Unmarshal(respBody, &br); err != nil {
-		return nil, fmt.Errorf("error parsing Bedrock response: %w", err)
+// ChatStream es Chat entregando el texto por partes a onText. Si el cliente no soporta streaming,
+// onText recibe la respuesta completa de una vez.
+func (b *Service) ChatStream(ctx context.Context, system string, messages []Message, onText func(text string) error, tools ...Tool) (*Completion, error) {
+	req := b.request(messages...)
+	req.System = system
+	req.Tools = tools
+
+	streaming, ok := b.client.(StreamingClient)
+	if !ok {
+		completion, err := b.client.Complete(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if completion.Text != "" {
+			if err := onText(completion.Text); err != nil {
+				return nil, err
+			}
+		}
+		return completion, nil
 	}
+	return streaming.Stream(ctx, req, onText)
+}
 
-	if len(br.Output.Message.Content) == 0 {
-		return nil, fmt.Errorf("no content in response")
+func (b *Service) request(messages ...Message) CompletionRequest {
+	return CompletionRequest{
+		Model:       b.model,
+		Messages:    messages,
+		MaxTokens:   b.maxTokens,
+		Temperature: b.temperature,
 	}
+}
 
-	// 2. Extraer el texto
-	raw := br.Output.Message.Content[0].Text
-
-	// 3. Limpiar los backticks y el ```json
-	raw = strings.TrimSpace(raw)
-	raw = strings.TrimPrefix(raw, "```json")
-	raw = strings.TrimPrefix(raw, "```")
-	raw = strings.TrimSuffix(raw, "```")
-	raw = strings.TrimSpace(raw)
+// ExtractionAttempt es una llamada al modelo durante FormatProduct: la original o una reparación
+type ExtractionAttempt struct {
+	Attempt int
+	Model   string
+	Output  string
+	Errors  []string
+	Usage   Usage
+}
 
-	// 4. Unmarshal al modelo
-	var products []ProductResponse
-	if err := json.Unmarshal([]byte(raw), &products); err != nil {
-		return nil, fmt.Errorf("error unmarshalling product json: %w", err)
+// FormatProduct pide los productos al modelo y valida la salida contra ProductSchema. Si no cumple, reenvía
+// los errores al modelo hasta repairAttempts veces. Devuelve todas las llamadas hechas, también cuando falla.
+func (b *Service) FormatProduct(ctx context.Context, input string, promptPremilinar string) (*[]ProductResponse, []ExtractionAttempt, error) {
+	prompt := fmt.Sprintf(promptPremilinar, input)
+	messages := []Message{{Role: RoleUser, Text: prompt}}
+
+	attempts := make([]ExtractionAttempt, 0, 1)
+	for i := 0; i <= b.repairAttempts; i++ {
+		completion, err := b.client.Complete(ctx, b.request(messages...))
+		if err != nil {
+			return nil, attempts, err
+		}
+
+		attempt := ExtractionAttempt{Attempt: i + 1, Model: b.model, Output: completion.Text, Usage: completion.Usage}
+
+		products, err := decodeProducts(completion.Text)
+		if err == nil {
+			attempts = append(attempts, attempt)
+			return &products, attempts, nil
+		}
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil, attempts, err
+		}
+		attempt.Errors = validationErr.Errors
+		attempts = append(attempts, attempt)
+		log.Printf("Salida del modelo inválida (intento %d): %v", i+1, err)
+
+		if i == b.repairAttempts {
+			return nil, attempts, err
+		}
+		messages = append(messages,
+			Message{Role: RoleAssistant, Text: completion.Text},
+			Message{Role: RoleUser, Text: repairPrompt(validationErr)},
+		)
 	}
 
-	return products, nil
+	return nil, attempts, fmt.Errorf("sin intentos de extracción")
+}
+
+func repairPrompt(err *ValidationError) string {
+	return "Tu respuesta anterior no cumple el esquema JSON requerido.\nErrores:\n- " +
+		strings.Join(err.Errors, "\n- ") +
+		"\nEsquema:\n" + ProductSchema +
+		"\nDevuelve solo el JSON corregido, sin texto adicional."
 }
 
+// ChatBot responde con la forma de respuesta de Nova (output.message.content[].text), que es la que consume el front,
+// sin importar el proveedor configurado
 func (b *Service) ChatBot(ctx context.Context, input string, promptPremilinar string) (map[string]interface{}, error) {
 	prompt := fmt.Sprintf(promptPremilinar, input)
 
-	body := fmt.Sprintf(`{
-      "messages": [
-        {
-          "role": "user",
-          "content": [
-            { "text": %q }
-          ]
-        }
-      ],
-      "inferenceConfig": {
-        "maxTokens": 712,
-        "temperature": 0
-      }
-    }`, prompt)
-
-	resp, err := b.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(NOVA_PRO_AWS),
-		ContentType: aws.String("application/json"),
-		Accept:      aws.String("application/json"),
-		Body:        []byte(body),
-	})
+	completion, err := b.complete(ctx, prompt)
 	if err != nil {
-		log.Println("Error invoking Bedrock model:", err)
-		return nil, err
-	}
-
-	var parsed map[string]interface{}
-	if err := json.Unmarshal(resp.Body, &parsed); err != nil {
 		return nil, err
 	}
 
-	return parsed, nil
+	return map[string]interface{}{
+		"output": map[string]interface{}{
+			"message": map[string]interface{}{
+				"role":    RoleAssistant,
+				"content": []map[string]interface{}{{"text": completion.Text}},
+			},
+		},
+		"stopReason": completion.StopReason,
+		"usage": map[string]interface{}{
+			"inputTokens":  completion.Usage.InputTokens,
+			"outputTokens": completion.Usage.OutputTokens,
+			"totalTokens":  completion.Usage.InputTokens + completion.Usage.OutputTokens,
+		},
+	}, nil
 }
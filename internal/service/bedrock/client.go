@@ -0,0 +1,104 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/stock-ahora/api-stock/internal/config"
+)
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+type Message struct {
+	Role string
+	Text string
+	// ToolCalls son las herramientas que pidió el modelo (solo en turnos de assistant)
+	ToolCalls []ToolCall
+	// ToolResults responden a los ToolCalls del turno anterior (solo en turnos de user)
+	ToolResults []ToolResult
+}
+
+// Tool es una función que el modelo puede pedir ejecutar; InputSchema es el JSON Schema de sus argumentos
+type Tool struct {
+	Name        string
+	Description string
+	InputSchema json.RawMessage
+}
+
+type ToolCall struct {
+	ID    string
+	Name  string
+	Input json.RawMessage
+}
+
+type ToolResult struct {
+	ToolCallID string
+	Content    string
+	IsError    bool
+}
+
+// CompletionRequest es una invocación independiente del proveedor; cada cliente la traduce a su formato
+type CompletionRequest struct {
+	Model       string
+	System      string
+	Messages    []Message
+	Tools       []Tool
+	MaxTokens   int
+	Temperature float64
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Completion es la respuesta del modelo; si pidió herramientas, ToolCalls viene con datos y Text puede venir vacío
+type Completion struct {
+	Text       string
+	ToolCalls  []ToolCall
+	StopReason string
+	Usage      Usage
+}
+
+// Client invoca un modelo de lenguaje
+type Client interface {
+	Complete(ctx context.Context, req CompletionRequest) (*Completion, error)
+}
+
+// NewClient arma el cliente del proveedor configurado
+func NewClient(ctx context.Context, cfg config.LLMConfig) (Client, error) {
+	switch cfg.Provider {
+	case config.LLMProviderBedrock:
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.Region))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %w", err)
+		}
+		return NewBedrockClient(bedrockruntime.NewFromConfig(awsCfg)), nil
+	case config.LLMProviderOpenAI:
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("LLM_MODEL es obligatorio con el proveedor %s", cfg.Provider)
+		}
+		return NewOpenAIClient(cfg.BaseURL, cfg.APIKey), nil
+	case config.LLMProviderNone:
+		return disabledClient{}, nil
+	default:
+		return nil, fmt.Errorf("proveedor de LLM desconocido: %s", cfg.Provider)
+	}
+}
+
+// ErrLLMDisabled es el error de toda llamada al modelo con LLM_PROVIDER=none
+var ErrLLMDisabled = errors.New("no hay un modelo de lenguaje configurado (LLM_PROVIDER=none)")
+
+// disabledClient es el cliente sin proveedor: deja levantar la API sin credenciales de un modelo
+type disabledClient struct{}
+
+func (disabledClient) Complete(context.Context, CompletionRequest) (*Completion, error) {
+	return nil, ErrLLMDisabled
+}
@@ -0,0 +1,280 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+// messageFormat traduce una CompletionRequest al cuerpo que espera una familia de modelos de Bedrock y su respuesta de vuelta
+type messageFormat interface {
+	buildRequest(req CompletionRequest) ([]byte, error)
+	parseResponse(body []byte) (*Completion, error)
+	// parseStreamChunk acumula un fragmento del stream en state y devuelve el texto nuevo, si trae
+	parseStreamChunk(chunk []byte, state *streamState) (string, error)
+}
+
+func formatForModel(model string) messageFormat {
+	if strings.Contains(model, "anthropic.") {
+		return claudeFormat{}
+	}
+	return novaFormat{}
+}
+
+// bedrockClient invoca modelos de Bedrock con InvokeModel, eligiendo el formato según el id del modelo
+type bedrockClient struct {
+	client *bedrockruntime.Client
+}
+
+func NewBedrockClient(client *bedrockruntime.Client) Client {
+	return &bedrockClient{client: client}
+}
+
+func (c *bedrockClient) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	format := formatForModel(req.Model)
+
+	body, err := format.buildRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(req.Model),
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		log.Println("Error invoking Bedrock model:", err)
+		return nil, err
+	}
+
+	log.Println("Raw response:", string(resp.Body))
+
+	return format.parseResponse(resp.Body)
+}
+
+// Amazon Nova (messages-v1)
+
+type novaContent struct {
+	Text       string          `json:"text,omitempty"`
+	ToolUse    *novaToolUse    `json:"toolUse,omitempty"`
+	ToolResult *novaToolResult `json:"toolResult,omitempty"`
+}
+
+type novaToolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+}
+
+type novaToolResult struct {
+	ToolUseID string        `json:"toolUseId"`
+	Content   []novaContent `json:"content"`
+	Status    string        `json:"status,omitempty"`
+}
+
+type novaMessage struct {
+	Role    string        `json:"role"`
+	Content []novaContent `json:"content"`
+}
+
+type novaToolSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	InputSchema struct {
+		JSON json.RawMessage `json:"json"`
+	} `json:"inputSchema"`
+}
+
+type novaTool struct {
+	ToolSpec novaToolSpec `json:"toolSpec"`
+}
+
+type novaToolConfig struct {
+	Tools []novaTool `json:"tools"`
+}
+
+type novaRequest struct {
+	System          []novaContent   `json:"system,omitempty"`
+	Messages        []novaMessage   `json:"messages"`
+	ToolConfig      *novaToolConfig `json:"toolConfig,omitempty"`
+	InferenceConfig struct {
+		MaxTokens   int     `json:"maxTokens"`
+		Temperature float64 `json:"temperature"`
+	} `json:"inferenceConfig"`
+}
+
+type novaResponse struct {
+	Output struct {
+		Message novaMessage `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+	} `json:"usage"`
+}
+
+type novaFormat struct{}
+
+func (novaFormat) buildRequest(req CompletionRequest) ([]byte, error) {
+	body := novaRequest{}
+	if req.System != "" {
+		body.System = []novaContent{{Text: req.System}}
+	}
+	for _, m := range req.Messages {
+		var content []novaContent
+		if m.Text != "" {
+			content = append(content, novaContent{Text: m.Text})
+		}
+		for _, call := range m.ToolCalls {
+			content = append(content, novaContent{ToolUse: &novaToolUse{ToolUseID: call.ID, Name: call.Name, Input: call.Input}})
+		}
+		for _, result := range m.ToolResults {
+			toolResult := &novaToolResult{ToolUseID: result.ToolCallID, Content: []novaContent{{Text: result.Content}}}
+			if result.IsError {
+				toolResult.Status = "error"
+			}
+			content = append(content, novaContent{ToolResult: toolResult})
+		}
+		body.Messages = append(body.Messages, novaMessage{Role: m.Role, Content: content})
+	}
+	if len(req.Tools) > 0 {
+		body.ToolConfig = &novaToolConfig{}
+		for _, tool := range req.Tools {
+			spec := novaToolSpec{Name: tool.Name, Description: tool.Description}
+			spec.InputSchema.JSON = tool.InputSchema
+			body.ToolConfig.Tools = append(body.ToolConfig.Tools, novaTool{ToolSpec: spec})
+		}
+	}
+	body.InferenceConfig.MaxTokens = req.MaxTokens
+	body.InferenceConfig.Temperature = req.Temperature
+	return json.Marshal(body)
+}
+
+func (novaFormat) parseResponse(raw []byte) (*Completion, error) {
+	var resp novaResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("error parsing Bedrock response: %w", err)
+	}
+	if len(resp.Output.Message.Content) == 0 {
+		return nil, fmt.Errorf("no content in response")
+	}
+
+	completion := &Completion{
+		StopReason: resp.StopReason,
+		Usage:      Usage{InputTokens: resp.Usage.InputTokens, OutputTokens: resp.Usage.OutputTokens},
+	}
+	var text strings.Builder
+	for _, c := range resp.Output.Message.Content {
+		text.WriteString(c.Text)
+		if c.ToolUse != nil {
+			completion.ToolCalls = append(completion.ToolCalls, ToolCall{ID: c.ToolUse.ToolUseID, Name: c.ToolUse.Name, Input: c.ToolUse.Input})
+		}
+	}
+	completion.Text = text.String()
+	return completion, nil
+}
+
+// Anthropic Claude (Messages API en Bedrock)
+
+const claudeAnthropicVersion = "bedrock-2023-05-31"
+
+type claudeContent struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+type claudeMessage struct {
+	Role    string          `json:"role"`
+	Content []claudeContent `json:"content"`
+}
+
+type claudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type claudeRequest struct {
+	AnthropicVersion string          `json:"anthropic_version"`
+	MaxTokens        int             `json:"max_tokens"`
+	Temperature      float64         `json:"temperature"`
+	System           string          `json:"system,omitempty"`
+	Messages         []claudeMessage `json:"messages"`
+	Tools            []claudeTool    `json:"tools,omitempty"`
+}
+
+type claudeResponse struct {
+	Content    []claudeContent `json:"content"`
+	StopReason string          `json:"stop_reason"`
+	Usage      Usage           `json:"usage"`
+}
+
+type claudeFormat struct{}
+
+func (claudeFormat) buildRequest(req CompletionRequest) ([]byte, error) {
+	body := claudeRequest{
+		AnthropicVersion: claudeAnthropicVersion,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		System:           req.System,
+	}
+	for _, m := range req.Messages {
+		var content []claudeContent
+		// los resultados de herramientas van antes que cualquier texto del turno
+		for _, result := range m.ToolResults {
+			content = append(content, claudeContent{Type: "tool_result", ToolUseID: result.ToolCallID, Content: result.Content, IsError: result.IsError})
+		}
+		if m.Text != "" {
+			content = append(content, claudeContent{Type: "text", Text: m.Text})
+		}
+		for _, call := range m.ToolCalls {
+			content = append(content, claudeContent{Type: "tool_use", ID: call.ID, Name: call.Name, Input: call.Input})
+		}
+		body.Messages = append(body.Messages, claudeMessage{Role: m.Role, Content: content})
+	}
+	for _, tool := range req.Tools {
+		body.Tools = append(body.Tools, claudeTool{Name: tool.Name, Description: tool.Description, InputSchema: tool.InputSchema})
+	}
+	return json.Marshal(body)
+}
+
+func (claudeFormat) parseResponse(raw []byte) (*Completion, error) {
+	var resp claudeResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("error parsing Bedrock response: %w", err)
+	}
+
+	completion := &Completion{StopReason: resp.StopReason, Usage: resp.Usage}
+	var text strings.Builder
+	for _, c := range resp.Content {
+		switch c.Type {
+		case "text":
+			text.WriteString(c.Text)
+		case "tool_use":
+			completion.ToolCalls = append(completion.ToolCalls, ToolCall{ID: c.ID, Name: c.Name, Input: c.Input})
+		}
+	}
+	if text.Len() == 0 && len(completion.ToolCalls) == 0 {
+		return nil, fmt.Errorf("no content in response")
+	}
+	completion.Text = text.String()
+	return completion, nil
+}
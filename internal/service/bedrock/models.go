@@ -3,3 +3,15 @@ package bedrock
 const CLAUDE_SONE_V3 = "anthropic.claude-3-haiku-20240307-v1:0"
 const NOVA_LITE_AWS = "amazon.nova-lite-v1:0"
 const NOVA_PRO_AWS = "amazon.nova-pro-v1:0"
+
+// SupportedModels son los modelos de Bedrock con formato de mensajes conocido (Nova o Claude)
+var SupportedModels = []string{NOVA_PRO_AWS, NOVA_LITE_AWS, CLAUDE_SONE_V3}
+
+func IsSupportedModel(model string) bool {
+	for _, m := range SupportedModels {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
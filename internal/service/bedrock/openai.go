@@ -0,0 +1,262 @@
+package bedrock
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// openAIClient habla con cualquier servidor compatible con /chat/completions de OpenAI (vLLM, Ollama, LM Studio...)
+type openAIClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func NewOpenAIClient(baseURL string, apiKey string) Client {
+	return &openAIClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Tools       []openAITool    `json:"tools,omitempty"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Temperature float64         `json:"temperature"`
+
+	Stream        bool `json:"stream,omitempty"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func buildOpenAIRequest(req CompletionRequest) openAIRequest {
+	body := openAIRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		// cada resultado de herramienta es un mensaje propio con rol tool
+		for _, result := range m.ToolResults {
+			body.Messages = append(body.Messages, openAIMessage{Role: "tool", Content: result.Content, ToolCallID: result.ToolCallID})
+		}
+		if len(m.ToolResults) > 0 && m.Text == "" {
+			continue
+		}
+
+		message := openAIMessage{Role: m.Role, Content: m.Text}
+		for _, call := range m.ToolCalls {
+			toolCall := openAIToolCall{ID: call.ID, Type: "function"}
+			toolCall.Function.Name = call.Name
+			toolCall.Function.Arguments = string(call.Input)
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+		}
+		body.Messages = append(body.Messages, message)
+	}
+	for _, t := range req.Tools {
+		tool := openAITool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.InputSchema
+		body.Tools = append(body.Tools, tool)
+	}
+	return body
+}
+
+// post envía el cuerpo a /chat/completions; una respuesta que no es 200 se devuelve como error
+func (c *openAIClient) post(ctx context.Context, body openAIRequest) (*http.Response, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("servidor LLM respondió %d: %s", resp.StatusCode, string(respBody))
+	}
+	return resp, nil
+}
+
+func (c *openAIClient) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	resp, err := c.post(ctx, buildOpenAIRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed openAIResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing LLM response: %w", err)
+	}
+	if len(parsed.Choices) == 0 {
+		return nil, fmt.Errorf("no content in response")
+	}
+
+	completion := &Completion{
+		Text:       parsed.Choices[0].Message.Content,
+		StopReason: parsed.Choices[0].FinishReason,
+		Usage: Usage{
+			InputTokens:  parsed.Usage.PromptTokens,
+			OutputTokens: parsed.Usage.CompletionTokens,
+		},
+	}
+	for _, call := range parsed.Choices[0].Message.ToolCalls {
+		completion.ToolCalls = append(completion.ToolCalls, ToolCall{
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: json.RawMessage(call.Function.Arguments),
+		})
+	}
+	return completion, nil
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// Stream lee la respuesta como Server-Sent Events ("data: {...}" hasta "data: [DONE]")
+func (c *openAIClient) Stream(ctx context.Context, req CompletionRequest, onText func(text string) error) (*Completion, error) {
+	body := buildOpenAIRequest(req)
+	body.Stream = true
+	body.StreamOptions = &struct {
+		IncludeUsage bool `json:"include_usage"`
+	}{IncludeUsage: true}
+
+	resp, err := c.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	state := newStreamState()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("error parsing LLM stream: %w", err)
+		}
+		if chunk.Usage != nil {
+			state.completion.Usage = Usage{InputTokens: chunk.Usage.PromptTokens, OutputTokens: chunk.Usage.CompletionTokens}
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			state.completion.StopReason = choice.FinishReason
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			if call.ID != "" {
+				state.startTool(call.Index, call.ID, call.Function.Name)
+			}
+			state.addToolInput(call.Index, call.Function.Arguments)
+		}
+		if choice.Delta.Content != "" {
+			state.text.WriteString(choice.Delta.Content)
+			if err := onText(choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return state.finish(), nil
+}
@@ -0,0 +1,220 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+// StreamingClient es un Client que además entrega el texto a medida que el modelo lo genera.
+// onText se llama con cada fragmento; si devuelve error la invocación se corta.
+type StreamingClient interface {
+	Client
+	Stream(ctx context.Context, req CompletionRequest, onText func(text string) error) (*Completion, error)
+}
+
+// streamState arma la Completion a partir de los fragmentos del stream; las herramientas llegan por índice de bloque
+// con sus argumentos en partes
+type streamState struct {
+	completion Completion
+	text       strings.Builder
+	tools      map[int]*ToolCall
+	toolInput  map[int]*strings.Builder
+}
+
+func newStreamState() *streamState {
+	return &streamState{tools: map[int]*ToolCall{}, toolInput: map[int]*strings.Builder{}}
+}
+
+func (s *streamState) startTool(index int, id string, name string) {
+	s.tools[index] = &ToolCall{ID: id, Name: name}
+	s.toolInput[index] = &strings.Builder{}
+}
+
+func (s *streamState) addToolInput(index int, partial string) {
+	if input, ok := s.toolInput[index]; ok {
+		input.WriteString(partial)
+	}
+}
+
+func (s *streamState) finish() *Completion {
+	completion := s.completion
+	completion.Text = s.text.String()
+
+	indexes := make([]int, 0, len(s.tools))
+	for i := range s.tools {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		call := *s.tools[i]
+		call.Input = json.RawMessage(s.toolInput[i].String())
+		if len(call.Input) == 0 {
+			call.Input = json.RawMessage("{}")
+		}
+		completion.ToolCalls = append(completion.ToolCalls, call)
+	}
+	return &completion
+}
+
+func (c *bedrockClient) Stream(ctx context.Context, req CompletionRequest, onText func(text string) error) (*Completion, error) {
+	format := formatForModel(req.Model)
+
+	body, err := format.buildRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(req.Model),
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	stream := resp.GetStream()
+	defer stream.Close()
+
+	state := newStreamState()
+	for event := range stream.Events() {
+		chunk, ok := event.(*types.ResponseStreamMemberChunk)
+		if !ok {
+			continue
+		}
+		text, err := format.parseStreamChunk(chunk.Value.Bytes, state)
+		if err != nil {
+			return nil, err
+		}
+		if text == "" {
+			continue
+		}
+		state.text.WriteString(text)
+		if err := onText(text); err != nil {
+			return nil, err
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return state.finish(), nil
+}
+
+// Amazon Nova
+
+type novaStreamChunk struct {
+	ContentBlockStart *struct {
+		Start struct {
+			ToolUse *struct {
+				ToolUseID string `json:"toolUseId"`
+				Name      string `json:"name"`
+			} `json:"toolUse"`
+		} `json:"start"`
+		ContentBlockIndex int `json:"contentBlockIndex"`
+	} `json:"contentBlockStart"`
+	ContentBlockDelta *struct {
+		Delta struct {
+			Text    string `json:"text"`
+			ToolUse *struct {
+				Input string `json:"input"`
+			} `json:"toolUse"`
+		} `json:"delta"`
+		ContentBlockIndex int `json:"contentBlockIndex"`
+	} `json:"contentBlockDelta"`
+	MessageStop *struct {
+		StopReason string `json:"stopReason"`
+	} `json:"messageStop"`
+	Metadata *struct {
+		Usage struct {
+			InputTokens  int `json:"inputTokens"`
+			OutputTokens int `json:"outputTokens"`
+		} `json:"usage"`
+	} `json:"metadata"`
+}
+
+func (novaFormat) parseStreamChunk(raw []byte, state *streamState) (string, error) {
+	var chunk novaStreamChunk
+	if err := json.Unmarshal(raw, &chunk); err != nil {
+		return "", fmt.Errorf("error parsing Bedrock stream: %w", err)
+	}
+
+	switch {
+	case chunk.ContentBlockStart != nil && chunk.ContentBlockStart.Start.ToolUse != nil:
+		tool := chunk.ContentBlockStart.Start.ToolUse
+		state.startTool(chunk.ContentBlockStart.ContentBlockIndex, tool.ToolUseID, tool.Name)
+	case chunk.ContentBlockDelta != nil:
+		if chunk.ContentBlockDelta.Delta.ToolUse != nil {
+			state.addToolInput(chunk.ContentBlockDelta.ContentBlockIndex, chunk.ContentBlockDelta.Delta.ToolUse.Input)
+			return "", nil
+		}
+		return chunk.ContentBlockDelta.Delta.Text, nil
+	case chunk.MessageStop != nil:
+		state.completion.StopReason = chunk.MessageStop.StopReason
+	case chunk.Metadata != nil:
+		state.completion.Usage = Usage{
+			InputTokens:  chunk.Metadata.Usage.InputTokens,
+			OutputTokens: chunk.Metadata.Usage.OutputTokens,
+		}
+	}
+	return "", nil
+}
+
+// Anthropic Claude
+
+type claudeStreamChunk struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message *struct {
+		Usage Usage `json:"usage"`
+	} `json:"message"`
+	ContentBlock *claudeContent `json:"content_block"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *Usage `json:"usage"`
+}
+
+func (claudeFormat) parseStreamChunk(raw []byte, state *streamState) (string, error) {
+	var chunk claudeStreamChunk
+	if err := json.Unmarshal(raw, &chunk); err != nil {
+		return "", fmt.Errorf("error parsing Bedrock stream: %w", err)
+	}
+
+	switch chunk.Type {
+	case "message_start":
+		if chunk.Message != nil {
+			state.completion.Usage.InputTokens = chunk.Message.Usage.InputTokens
+		}
+	case "content_block_start":
+		if chunk.ContentBlock != nil && chunk.ContentBlock.Type == "tool_use" {
+			state.startTool(chunk.Index, chunk.ContentBlock.ID, chunk.ContentBlock.Name)
+		}
+	case "content_block_delta":
+		if chunk.Delta == nil {
+			return "", nil
+		}
+		if chunk.Delta.Type == "input_json_delta" {
+			state.addToolInput(chunk.Index, chunk.Delta.PartialJSON)
+			return "", nil
+		}
+		return chunk.Delta.Text, nil
+	case "message_delta":
+		if chunk.Delta != nil {
+			state.completion.StopReason = chunk.Delta.StopReason
+		}
+		if chunk.Usage != nil {
+			state.completion.Usage.OutputTokens = chunk.Usage.OutputTokens
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,185 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/stock-ahora/api-stock/internal/utils"
+)
+
+// ProductSchema es el JSON Schema que debe cumplir la salida de extracción; se le reenvía al modelo al reparar
+const ProductSchema = `{
+  "type": "array",
+  "items": {
+    "type": "object",
+    "required": ["name", "count"],
+    "properties": {
+      "name": {"type": "string", "minLength": 1},
+      "count": {"type": "integer", "minimum": 1},
+      "skus": {"type": "array", "items": {"type": "string"}, "maxItems": 3}
+    }
+  }
+}`
+
+// ValidationError lista por qué la salida del modelo no cumple ProductSchema
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return "la respuesta del modelo no cumple el esquema: " + strings.Join(e.Errors, "; ")
+}
+
+// decodeProducts valida la salida contra ProductSchema con coerción tolerante:
+// texto alrededor del JSON, un objeto suelto en vez de un array y números como string ("12", "12,0", "1.000").
+// Una cantidad con fracción real ("2,5") es un error, no se redondea. Devuelve los productos válidos solo si no hubo errores.
+func decodeProducts(raw string) ([]ProductResponse, error) {
+	payload, ok := extractJSON(raw)
+	if !ok {
+		return nil, &ValidationError{Errors: []string{"la respuesta no contiene JSON"}}
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(payload), &value); err != nil {
+		return nil, &ValidationError{Errors: []string{fmt.Sprintf("JSON inválido: %v", err)}}
+	}
+
+	var items []any
+	switch v := value.(type) {
+	case []any:
+		items = v
+	case map[string]any:
+		// {"products": [...]} o un único producto
+		if wrapped, ok := firstArray(v, "products", "productos", "items"); ok {
+			items = wrapped
+		} else {
+			items = []any{v}
+		}
+	default:
+		return nil, &ValidationError{Errors: []string{"se esperaba un array de productos"}}
+	}
+
+	var errs []string
+	products := make([]ProductResponse, 0, len(items))
+	for i, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("item %d: se esperaba un objeto", i))
+			continue
+		}
+
+		product, itemErrs := coerceProduct(obj)
+		for _, e := range itemErrs {
+			errs = append(errs, fmt.Sprintf("item %d: %s", i, e))
+		}
+		if len(itemErrs) == 0 {
+			products = append(products, product)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	return products, nil
+}
+
+func coerceProduct(obj map[string]any) (ProductResponse, []string) {
+	var errs []string
+	product := ProductResponse{SKUs: []string{}}
+
+	switch name := obj["name"].(type) {
+	case string:
+		product.Name = strings.TrimSpace(name)
+	case float64:
+		product.Name = strconv.FormatFloat(name, 'f', -1, 64)
+	}
+	if product.Name == "" {
+		errs = append(errs, "name es obligatorio y debe ser texto")
+	}
+
+	count, err := coerceCount(obj["count"])
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	product.Count = count
+
+	switch skus := obj["skus"].(type) {
+	case nil:
+	case string:
+		if s := strings.TrimSpace(skus); s != "" {
+			product.SKUs = append(product.SKUs, s)
+		}
+	case []any:
+		for _, sku := range skus {
+			switch s := sku.(type) {
+			case string:
+				if s = strings.TrimSpace(s); s != "" {
+					product.SKUs = append(product.SKUs, s)
+				}
+			case float64:
+				product.SKUs = append(product.SKUs, strconv.FormatFloat(s, 'f', -1, 64))
+			default:
+				errs = append(errs, "skus debe ser un array de textos")
+			}
+		}
+	default:
+		errs = append(errs, "skus debe ser un array de textos")
+	}
+	if len(product.SKUs) > 3 {
+		product.SKUs = product.SKUs[:3]
+	}
+
+	return product, errs
+}
+
+func coerceCount(value any) (int, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("count debe ser un número entero (se recibió %v)", v)
+		}
+		if v < 1 {
+			return 0, fmt.Errorf("count debe ser mayor a 0 (se recibió %v)", v)
+		}
+		return int(v), nil
+	case string:
+		count, ok := utils.ParseQuantity(v)
+		if !ok {
+			return 0, fmt.Errorf("count %q no es una cantidad entera mayor a 0", v)
+		}
+		return count, nil
+	case nil:
+		return 0, fmt.Errorf("count es obligatorio")
+	default:
+		return 0, fmt.Errorf("count debe ser un número entero")
+	}
+}
+
+// extractJSON recorta el primer array u objeto JSON del texto (quita fences de markdown y texto alrededor)
+func extractJSON(raw string) (string, bool) {
+	start := strings.IndexAny(raw, "[{")
+	if start < 0 {
+		return "", false
+	}
+	closing := byte(']')
+	if raw[start] == '{' {
+		closing = '}'
+	}
+	end := strings.LastIndexByte(raw, closing)
+	if end < start {
+		return "", false
+	}
+	return raw[start : end+1], true
+}
+
+func firstArray(obj map[string]any, keys ...string) ([]any, bool) {
+	for _, key := range keys {
+		if arr, ok := obj[key].([]any); ok {
+			return arr, true
+		}
+	}
+	return nil, false
+}
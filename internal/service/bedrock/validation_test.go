@@ -0,0 +1,32 @@
+package bedrock
+
+import "testing"
+
+func TestCoerceCount(t *testing.T) {
+	tests := []struct {
+		in      any
+		want    int
+		wantErr bool
+	}{
+		{float64(12), 12, false},
+		{float64(2.5), 0, true},
+		{float64(0), 0, true},
+		{"12", 12, false},
+		{"12,0", 12, false},
+		{"1.234", 1234, false},
+		{"1.234,00", 1234, false},
+		{"1.234,5", 0, true},
+		{"2,5", 0, true},
+		{"1,000", 0, true},
+		{"doce", 0, true},
+		{nil, 0, true},
+		{true, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := coerceCount(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("coerceCount(%#v) = %d, %v; se esperaba %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
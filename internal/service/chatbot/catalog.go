@@ -0,0 +1,230 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/dashboard"
+	"github.com/stock-ahora/api-stock/internal/service/prompt"
+	"github.com/stock-ahora/api-stock/internal/service/usage"
+	"gorm.io/gorm"
+)
+
+const (
+	SourceProduct = "product"
+	SourceRequest = "request"
+
+	catalogDefaultDays = 90
+	catalogMaxProducts = 150
+	catalogMaxRequests = 50
+)
+
+// CatalogQuestion es una pregunta sobre todo el catálogo; sin Start/End se usan los últimos 90 días
+type CatalogQuestion struct {
+	Question string
+	Start    time.Time
+	End      time.Time
+}
+
+// catalogRequest es una solicitud del período con el proveedor y folio de su factura, si se conocen
+type catalogRequest struct {
+	dashboard.RequestActivity
+	Proveedor string `json:"proveedor,omitempty"`
+	Folio     string `json:"folio,omitempty"`
+}
+
+type catalogContext struct {
+	Periodo struct {
+		Desde string `json:"desde"`
+		Hasta string `json:"hasta"`
+	} `json:"periodo"`
+	Productos   []dashboard.ProductActivity  `json:"productos"`
+	PorMes      []dashboard.MovementOverTime `json:"por_mes"`
+	Solicitudes []catalogRequest             `json:"solicitudes"`
+}
+
+var sourceReference = regexp.MustCompile(`\s*\[(producto|solicitud):\s*([0-9a-fA-F-]{36})\]`)
+
+// AskCatalog responde con los agregados del esquema estrella de la cuenta: stock y movimiento por producto, totales
+// por mes y solicitudes del período. Las referencias que cita el modelo vuelven en Sources, solo si están en el contexto.
+func (s *service) AskCatalog(ctx context.Context, clientAccountId uuid.UUID, question CatalogQuestion) (dto.CatalogAnswerDto, error) {
+	text := strings.TrimSpace(question.Question)
+	if text == "" {
+		return dto.CatalogAnswerDto{}, fmt.Errorf("%w: la pregunta es obligatoria", ErrInvalidMessage)
+	}
+
+	end := question.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+	start := question.Start
+	if start.IsZero() {
+		start = end.AddDate(0, 0, -catalogDefaultDays)
+	}
+	if start.After(end) {
+		return dto.CatalogAnswerDto{}, fmt.Errorf("%w: start es posterior a end", ErrInvalidMessage)
+	}
+
+	data, err := s.catalogContext(ctx, clientAccountId, dashboard.Filter{Start: start, End: end})
+	if err != nil {
+		return dto.CatalogAnswerDto{}, err
+	}
+
+	catalogPrompt, err := s.prompts.Resolve(ctx, prompt.UseCaseChatbotCatalog, clientAccountId, "")
+	if err != nil {
+		return dto.CatalogAnswerDto{}, err
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return dto.CatalogAnswerDto{}, err
+	}
+	input := fmt.Sprintf(catalogPrompt.Template, string(raw)+"\nPregunta: "+text)
+
+	completion, err := s.llm.Chat(ctx, "", []bedrock.Message{{Role: bedrock.RoleUser, Text: input}})
+	if err != nil {
+		return dto.CatalogAnswerDto{}, err
+	}
+	s.usage.RecordLLM(ctx, clientAccountId, nil, usage.OperationChatbotCatalog, s.llm.Model(), completion.Usage)
+
+	answer, sources := catalogSources(completion.Text, data)
+	return dto.CatalogAnswerDto{
+		Answer:  answer,
+		Sources: sources,
+		Start:   start,
+		End:     end,
+		Usage: dto.ChatUsageDto{
+			InputTokens:  completion.Usage.InputTokens,
+			OutputTokens: completion.Usage.OutputTokens,
+			TotalTokens:  completion.Usage.InputTokens + completion.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+func (s *service) catalogContext(ctx context.Context, clientAccountId uuid.UUID, filter dashboard.Filter) (catalogContext, error) {
+	var data catalogContext
+	data.Periodo.Desde = filter.Start.Format(dateLayout)
+	data.Periodo.Hasta = filter.End.Format(dateLayout)
+
+	// una cuenta sin movimientos todavía no está en dim_cliente: igual se listan sus productos
+	clienteID, err := s.sources.Dashboard.GetDimClienteID(clientAccountId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return data, err
+	}
+
+	if data.Productos, err = s.sources.Dashboard.GetProductActivity(clienteID, clientAccountId, filter, catalogMaxProducts); err != nil {
+		return data, err
+	}
+	monthly := filter
+	monthly.Period = "month"
+	if data.PorMes, err = s.sources.Dashboard.GetMovementOverTime(clienteID, monthly); err != nil {
+		return data, err
+	}
+
+	activity, err := s.sources.Dashboard.GetRequestActivity(clienteID, filter, catalogMaxRequests)
+	if err != nil {
+		return data, err
+	}
+	invoices, err := s.requestInvoices(ctx, clientAccountId, activity)
+	if err != nil {
+		return data, err
+	}
+	data.Solicitudes = make([]catalogRequest, 0, len(activity))
+	for _, a := range activity {
+		request := catalogRequest{RequestActivity: a}
+		if document, ok := invoices[a.SolicitudUUID]; ok {
+			request.Proveedor = document.SupplierName
+			request.Folio = document.InvoiceFolio
+		}
+		data.Solicitudes = append(data.Solicitudes, request)
+	}
+	return data, nil
+}
+
+// requestInvoices busca en la base operacional el proveedor y folio de cada solicitud, solo de la cuenta
+func (s *service) requestInvoices(ctx context.Context, clientAccountId uuid.UUID, activity []dashboard.RequestActivity) (map[uuid.UUID]models.Documents, error) {
+	invoices := make(map[uuid.UUID]models.Documents)
+	if len(activity) == 0 {
+		return invoices, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(activity))
+	for _, a := range activity {
+		ids = append(ids, a.SolicitudUUID)
+	}
+
+	var documents []models.Documents
+	err := s.db.WithContext(ctx).
+		Joins("JOIN request r ON r.id = documents.request_id").
+		Where("documents.request_id IN ? AND r.client_account_id = ?", ids, clientAccountId).
+		Where("documents.supplier_name IS NOT NULL OR documents.invoice_folio IS NOT NULL").
+		Order("documents.created_at").
+		Find(&documents).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, document := range documents {
+		if _, ok := invoices[document.RequestID]; !ok {
+			invoices[document.RequestID] = document
+		}
+	}
+	return invoices, nil
+}
+
+// catalogSources quita las referencias [producto:id] y [solicitud:id] del texto y las devuelve como fuentes,
+// sin repetir y descartando ids que no estaban en el contexto
+func catalogSources(text string, data catalogContext) (string, []dto.CatalogSourceDto) {
+	products := make(map[uuid.UUID]string, len(data.Productos))
+	for _, p := range data.Productos {
+		products[p.ProductoUUID] = p.Nombre
+	}
+	requests := make(map[uuid.UUID]string, len(data.Solicitudes))
+	for _, r := range data.Solicitudes {
+		label := r.Fecha.Format(dateLayout)
+		if r.Folio != "" {
+			label = "Folio " + r.Folio
+		}
+		if r.Proveedor != "" {
+			label += " - " + r.Proveedor
+		}
+		requests[r.SolicitudUUID] = label
+	}
+
+	sources := make([]dto.CatalogSourceDto, 0)
+	seen := make(map[uuid.UUID]bool)
+	for _, match := range sourceReference.FindAllStringSubmatch(text, -1) {
+		id, err := uuid.Parse(match[2])
+		if err != nil || seen[id] {
+			continue
+		}
+
+		source := dto.CatalogSourceDto{ID: id}
+		if match[1] == "producto" {
+			label, ok := products[id]
+			if !ok {
+				continue
+			}
+			source.Type, source.Label = SourceProduct, label
+		} else {
+			label, ok := requests[id]
+			if !ok {
+				continue
+			}
+			source.Type, source.Label = SourceRequest, label
+		}
+		seen[id] = true
+		sources = append(sources, source)
+	}
+
+	return strings.TrimSpace(sourceReference.ReplaceAllString(text, "")), sources
+}
@@ -0,0 +1,388 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/prompt"
+	"github.com/stock-ahora/api-stock/internal/service/usage"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionNotFound = errors.New("sesión de chat no encontrada")
+	ErrProductNotFound = errors.New("producto no encontrado")
+	ErrInvalidMessage  = errors.New("mensaje inválido")
+)
+
+const (
+	maxTitleLength = 60
+	defaultTitle   = "Nueva conversación"
+	// roundSeparator separa el texto de una ronda del de la anterior, en el stream y en la respuesta guardada
+	roundSeparator = "\n\n"
+)
+
+type Service interface {
+	CreateSession(ctx context.Context, clientAccountId uuid.UUID, userId string, session dto.CreateChatSessionDto) (dto.ChatSessionDto, error)
+	ListSessions(ctx context.Context, clientAccountId uuid.UUID, userId string) ([]dto.ChatSessionDto, error)
+	GetSession(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID) (dto.ChatSessionDto, error)
+	DeleteSession(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID) error
+	// SendMessage agrega la pregunta a la sesión, la envía al modelo con los turnos anteriores que caben en el
+	// presupuesto de tokens y guarda ambos turnos solo si el modelo respondió
+	SendMessage(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID, message dto.ChatMessageRequestDto) (dto.ChatMessageDto, error)
+	// SendMessageStream es SendMessage entregando el texto de la respuesta a onText a medida que llega.
+	// Si ctx se cancela (el cliente se desconectó) se corta la llamada al modelo y no se guarda nada.
+	SendMessageStream(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID, message dto.ChatMessageRequestDto, onText func(text string) error) (dto.ChatMessageDto, error)
+	// AskCatalog responde una pregunta sobre todo el catálogo de la cuenta, sin sesión
+	AskCatalog(ctx context.Context, clientAccountId uuid.UUID, question CatalogQuestion) (dto.CatalogAnswerDto, error)
+}
+
+type service struct {
+	db           *gorm.DB
+	llm          *bedrock.Service
+	prompts      prompt.Registry
+	sources      Sources
+	usage        usage.Service
+	historyLimit int
+}
+
+func NewService(db *gorm.DB, llm *bedrock.Service, prompts prompt.Registry, sources Sources, usageSvc usage.Service, historyTokenBudget int) Service {
+	return &service{db: db, llm: llm, prompts: prompts, sources: sources, usage: usageSvc, historyLimit: historyTokenBudget}
+}
+
+func (s *service) CreateSession(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionDto dto.CreateChatSessionDto) (dto.ChatSessionDto, error) {
+	session := models.ChatSession{
+		ID:              uuid.New(),
+		ClientAccountID: clientAccountId,
+		UserID:          userId,
+		Title:           strings.TrimSpace(sessionDto.Title),
+		ProductID:       sessionDto.ProductId,
+	}
+
+	if session.ProductID != nil {
+		var product models.Product
+		err := s.db.WithContext(ctx).First(&product, "id = ? AND client_account_id = ?", *session.ProductID, clientAccountId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.ChatSessionDto{}, ErrProductNotFound
+		}
+		if err != nil {
+			return dto.ChatSessionDto{}, err
+		}
+		if session.Title == "" {
+			session.Title = product.Name
+		}
+	}
+	if session.Title == "" {
+		session.Title = defaultTitle
+	}
+
+	if err := s.db.WithContext(ctx).Create(&session).Error; err != nil {
+		return dto.ChatSessionDto{}, err
+	}
+	return toSessionDto(session), nil
+}
+
+func (s *service) ListSessions(ctx context.Context, clientAccountId uuid.UUID, userId string) ([]dto.ChatSessionDto, error) {
+	var sessions []models.ChatSession
+	err := s.db.WithContext(ctx).
+		Where("client_account_id = ? AND user_id = ?", clientAccountId, userId).
+		Order("updated_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.ChatSessionDto, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, toSessionDto(session))
+	}
+	return items, nil
+}
+
+func (s *service) GetSession(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID) (dto.ChatSessionDto, error) {
+	session, err := s.findSession(ctx, clientAccountId, userId, sessionId)
+	if err != nil {
+		return dto.ChatSessionDto{}, err
+	}
+
+	messages, err := s.messages(ctx, sessionId)
+	if err != nil {
+		return dto.ChatSessionDto{}, err
+	}
+
+	sessionDto := toSessionDto(session)
+	sessionDto.Messages = make([]dto.ChatMessageDto, 0, len(messages))
+	for _, m := range messages {
+		sessionDto.Messages = append(sessionDto.Messages, toMessageDto(m))
+	}
+	return sessionDto, nil
+}
+
+func (s *service) DeleteSession(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND client_account_id = ? AND user_id = ?", sessionId, clientAccountId, userId).
+		Delete(&models.ChatSession{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *service) SendMessage(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID, message dto.ChatMessageRequestDto) (dto.ChatMessageDto, error) {
+	return s.send(ctx, clientAccountId, userId, sessionId, message, nil)
+}
+
+func (s *service) SendMessageStream(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID, message dto.ChatMessageRequestDto, onText func(text string) error) (dto.ChatMessageDto, error) {
+	return s.send(ctx, clientAccountId, userId, sessionId, message, onText)
+}
+
+func (s *service) send(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID, message dto.ChatMessageRequestDto, onText func(text string) error) (dto.ChatMessageDto, error) {
+	content := strings.TrimSpace(message.Content)
+	if content == "" {
+		return dto.ChatMessageDto{}, fmt.Errorf("%w: content es obligatorio", ErrInvalidMessage)
+	}
+
+	session, err := s.findSession(ctx, clientAccountId, userId, sessionId)
+	if err != nil {
+		return dto.ChatMessageDto{}, err
+	}
+
+	previous, err := s.messages(ctx, sessionId)
+	if err != nil {
+		return dto.ChatMessageDto{}, err
+	}
+
+	system, err := s.systemPrompt(ctx, session)
+	if err != nil {
+		return dto.ChatMessageDto{}, err
+	}
+
+	question := models.ChatMessage{
+		ID:        uuid.New(),
+		SessionID: sessionId,
+		Role:      bedrock.RoleUser,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+
+	conversation := append(history(previous, s.historyLimit), bedrock.Message{Role: bedrock.RoleUser, Text: content})
+	scope := &toolScope{db: s.db, sources: s.sources, clientAccountId: clientAccountId}
+	completion, err := s.converse(ctx, system, conversation, scope, onText)
+	if err != nil {
+		return dto.ChatMessageDto{}, err
+	}
+
+	answer := models.ChatMessage{
+		ID:           uuid.New(),
+		SessionID:    sessionId,
+		Role:         bedrock.RoleAssistant,
+		Content:      strings.TrimSpace(completion.Text),
+		Model:        s.llm.Model(),
+		InputTokens:  completion.Usage.InputTokens,
+		OutputTokens: completion.Usage.OutputTokens,
+		CreatedAt:    time.Now(),
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&question).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&answer).Error; err != nil {
+			return err
+		}
+		updates := map[string]any{"updated_at": answer.CreatedAt}
+		if len(previous) == 0 && session.ProductID == nil && session.Title == defaultTitle {
+			updates["title"] = truncate(content, maxTitleLength)
+		}
+		return tx.Model(&models.ChatSession{}).Where("id = ?", sessionId).Updates(updates).Error
+	})
+	if err != nil {
+		return dto.ChatMessageDto{}, err
+	}
+
+	return toMessageDto(answer), nil
+}
+
+func (s *service) findSession(ctx context.Context, clientAccountId uuid.UUID, userId string, sessionId uuid.UUID) (models.ChatSession, error) {
+	var session models.ChatSession
+	err := s.db.WithContext(ctx).
+		First(&session, "id = ? AND client_account_id = ? AND user_id = ?", sessionId, clientAccountId, userId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ChatSession{}, ErrSessionNotFound
+	}
+	return session, err
+}
+
+func (s *service) messages(ctx context.Context, sessionId uuid.UUID) ([]models.ChatMessage, error) {
+	var messages []models.ChatMessage
+	err := s.db.WithContext(ctx).Where("session_id = ?", sessionId).Order("created_at").Find(&messages).Error
+	return messages, err
+}
+
+// converse envía la conversación con las herramientas y ejecuta las que pida el modelo hasta que responda con texto.
+// Devuelve la última respuesta con el consumo sumado de todas las llamadas, que quedan registradas una a una en el
+// consumo de la cuenta. Con onText cada llamada se hace en streaming; como no se sabe si una ronda es la última
+// hasta que termina, el texto de las rondas con herramientas también se envía, y el Text devuelto los junta todos
+// para que lo guardado sea lo mismo que vio el usuario.
+func (s *service) converse(ctx context.Context, system string, conversation []bedrock.Message, scope *toolScope, onText func(text string) error) (*bedrock.Completion, error) {
+	var total bedrock.Usage
+	var transcript strings.Builder
+	tools := toolSpecs()
+
+	for round := 0; ; round++ {
+		if round == maxToolRounds {
+			// se agotaron las rondas: se pide la respuesta con lo que ya consultó
+			tools = nil
+		}
+
+		var completion *bedrock.Completion
+		var err error
+		if onText != nil {
+			completion, err = s.llm.ChatStream(ctx, system, conversation, separateRounds(onText, transcript.Len() > 0), tools...)
+		} else {
+			completion, err = s.llm.Chat(ctx, system, conversation, tools...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.usage.RecordLLM(ctx, scope.clientAccountId, nil, usage.OperationChatSession, s.llm.Model(), completion.Usage)
+		total.InputTokens += completion.Usage.InputTokens
+		total.OutputTokens += completion.Usage.OutputTokens
+		if text := strings.TrimSpace(completion.Text); text != "" {
+			if transcript.Len() > 0 {
+				transcript.WriteString(roundSeparator)
+			}
+			transcript.WriteString(text)
+		}
+
+		if len(completion.ToolCalls) == 0 || tools == nil {
+			completion.Text = transcript.String()
+			completion.Usage = total
+			return completion, nil
+		}
+
+		results := make([]bedrock.ToolResult, 0, len(completion.ToolCalls))
+		for _, call := range completion.ToolCalls {
+			log.Printf("Chatbot: herramienta %s para la cuenta %s", call.Name, scope.clientAccountId)
+			results = append(results, scope.run(ctx, call))
+		}
+		conversation = append(conversation,
+			bedrock.Message{Role: bedrock.RoleAssistant, Text: completion.Text, ToolCalls: completion.ToolCalls},
+			bedrock.Message{Role: bedrock.RoleUser, ToolResults: results},
+		)
+	}
+}
+
+// separateRounds antepone roundSeparator al primer texto de la ronda cuando ya se envió texto de una anterior
+func separateRounds(onText func(text string) error, afterText bool) func(text string) error {
+	if !afterText {
+		return onText
+	}
+	separated := false
+	return func(text string) error {
+		if !separated && text != "" {
+			separated = true
+			text = roundSeparator + text
+		}
+		return onText(text)
+	}
+}
+
+// systemPrompt arma el prompt de sistema vigente del cliente con la fecha y, si la sesión es de un producto, cuál es.
+// Los datos los consulta el modelo con las herramientas.
+func (s *service) systemPrompt(ctx context.Context, session models.ChatSession) (string, error) {
+	chatPrompt, err := s.prompts.Resolve(ctx, prompt.UseCaseChatbotTools, session.ClientAccountID, "")
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]any{"fecha": time.Now().Format(dateLayout)}
+	if session.ProductID != nil {
+		var product models.Product
+		err := s.db.WithContext(ctx).First(&product, "id = ? AND client_account_id = ?", *session.ProductID, session.ClientAccountID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrProductNotFound
+		}
+		if err != nil {
+			return "", err
+		}
+		data["producto"] = map[string]any{"product_id": product.ID, "nombre": product.Name}
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(chatPrompt.Template, string(raw)), nil
+}
+
+// history devuelve los turnos más recientes cuya suma estimada de tokens cabe en budget.
+// La conversación reenviada siempre empieza con un turno del usuario, como exigen los proveedores.
+func history(messages []models.ChatMessage, budget int) []bedrock.Message {
+	start := len(messages)
+	used := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		used += estimateTokens(messages[i].Content)
+		if used > budget {
+			break
+		}
+		start = i
+	}
+	for start < len(messages) && messages[start].Role != bedrock.RoleUser {
+		start++
+	}
+
+	out := make([]bedrock.Message, 0, len(messages)-start+1)
+	for _, m := range messages[start:] {
+		out = append(out, bedrock.Message{Role: m.Role, Text: m.Content})
+	}
+	return out
+}
+
+// estimateTokens aproxima los tokens de un texto (~4 caracteres por token), suficiente para acotar el historial
+func estimateTokens(text string) int {
+	return utf8.RuneCountInString(text)/4 + 1
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max])) + "…"
+}
+
+func toSessionDto(s models.ChatSession) dto.ChatSessionDto {
+	return dto.ChatSessionDto{
+		ID:        s.ID,
+		Title:     s.Title,
+		ProductId: s.ProductID,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
+
+func toMessageDto(m models.ChatMessage) dto.ChatMessageDto {
+	return dto.ChatMessageDto{
+		ID:           m.ID,
+		Role:         m.Role,
+		Content:      m.Content,
+		Model:        m.Model,
+		InputTokens:  m.InputTokens,
+		OutputTokens: m.OutputTokens,
+		CreatedAt:    m.CreatedAt,
+	}
+}
@@ -0,0 +1,382 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/dashboard"
+	"github.com/stock-ahora/api-stock/internal/service/movement"
+	"github.com/stock-ahora/api-stock/internal/service/request"
+	"github.com/stock-ahora/api-stock/internal/service/stock"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxToolRounds acota las idas y vueltas con herramientas de un mensaje; después se pide la respuesta sin ellas
+	maxToolRounds = 6
+	// maxToolResultChars recorta resultados grandes para no agotar el contexto del modelo
+	maxToolResultChars = 8000
+	dateLayout         = "2006-01-02"
+)
+
+// Sources son los servicios que consultan las herramientas del chatbot
+type Sources struct {
+	Stock     stock.StockService
+	Movements movement.MovementService
+	Dashboard dashboard.DashboardService
+	Requests  request.RequestService
+}
+
+// toolScope ejecuta las herramientas de un mensaje; todas quedan acotadas a la cuenta cliente de la sesión
+type toolScope struct {
+	db              *gorm.DB
+	sources         Sources
+	clientAccountId uuid.UUID
+}
+
+type toolHandler func(ctx context.Context, scope *toolScope, input toolInput) (any, error)
+
+type chatTool struct {
+	spec bedrock.Tool
+	run  toolHandler
+}
+
+// toolInput son los argumentos que puede mandar el modelo; cada herramienta usa los suyos
+type toolInput struct {
+	ProductID string `json:"product_id"`
+	RequestID string `json:"request_id"`
+	Folio     string `json:"folio"`
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Period    string `json:"period"`
+	Page      int    `json:"page"`
+	Size      int    `json:"size"`
+	Limit     int    `json:"limit"`
+	Threshold *int   `json:"threshold"`
+}
+
+const (
+	schemaEmpty      = `{"type":"object","properties":{}}`
+	schemaPagination = `{"type":"object","properties":{"page":{"type":"integer","minimum":1},"size":{"type":"integer","minimum":1,"maximum":50}}}`
+	schemaDateRange  = `{"type":"object","properties":{"start":{"type":"string","description":"AAAA-MM-DD"},"end":{"type":"string","description":"AAAA-MM-DD"},"limit":{"type":"integer","minimum":1,"maximum":50}}}`
+)
+
+var chatTools = []chatTool{
+	{
+		spec: bedrock.Tool{
+			Name:        "list_products",
+			Description: "Lista los productos de la cuenta con su stock actual, paginados, los más recientes primero.",
+			InputSchema: json.RawMessage(schemaPagination),
+		},
+		run: listProducts,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "low_stock_products",
+			Description: "Lista los productos con stock menor o igual al umbral (por defecto 5), de menor a mayor stock.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"threshold":{"type":"integer","minimum":0},"limit":{"type":"integer","minimum":1,"maximum":50}}}`),
+		},
+		run: lowStockProducts,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "get_product",
+			Description: "Devuelve un producto por su id con su stock actual.",
+			InputSchema: json.RawMessage(`{"type":"object","required":["product_id"],"properties":{"product_id":{"type":"string","format":"uuid"}}}`),
+		},
+		run: getProduct,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "list_product_movements",
+			Description: "Lista los movimientos (ingresos y egresos) de un producto, los más recientes primero.",
+			InputSchema: json.RawMessage(`{"type":"object","required":["product_id"],"properties":{"product_id":{"type":"string","format":"uuid"},"page":{"type":"integer","minimum":1},"size":{"type":"integer","minimum":1,"maximum":50}}}`),
+		},
+		run: listProductMovements,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "top_products",
+			Description: "Productos con más movimiento (ingresos, egresos y total) en un rango de fechas opcional.",
+			InputSchema: json.RawMessage(schemaDateRange),
+		},
+		run: topProducts,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "movements_summary",
+			Description: "Total de unidades ingresadas y egresadas de la cuenta.",
+			InputSchema: json.RawMessage(schemaEmpty),
+		},
+		run: movementsSummary,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "movements_over_time",
+			Description: "Ingresos, egresos y stock acumulado por semana o por mes, en un rango de fechas opcional.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"period":{"type":"string","enum":["week","month"]},"start":{"type":"string","description":"AAAA-MM-DD"},"end":{"type":"string","description":"AAAA-MM-DD"}}}`),
+		},
+		run: movementsOverTime,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "movements_by_type",
+			Description: "Total de unidades por tipo de movimiento.",
+			InputSchema: json.RawMessage(schemaEmpty),
+		},
+		run: movementsByType,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "list_requests",
+			Description: "Lista las solicitudes de ingreso/egreso de la cuenta, paginadas; folio filtra por el folio de la factura.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"folio":{"type":"string"},"page":{"type":"integer","minimum":1},"size":{"type":"integer","minimum":1,"maximum":50}}}`),
+		},
+		run: listRequests,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "get_request",
+			Description: "Devuelve una solicitud con su factura, documentos y movimientos.",
+			InputSchema: json.RawMessage(`{"type":"object","required":["request_id"],"properties":{"request_id":{"type":"string","format":"uuid"}}}`),
+		},
+		run: getRequest,
+	},
+	{
+		spec: bedrock.Tool{
+			Name:        "supplier_summary",
+			Description: "Proveedores ordenados por unidades recibidas en un rango de fechas (por defecto los últimos 30 días).",
+			InputSchema: json.RawMessage(schemaDateRange),
+		},
+		run: supplierSummary,
+	},
+}
+
+func toolSpecs() []bedrock.Tool {
+	specs := make([]bedrock.Tool, 0, len(chatTools))
+	for _, t := range chatTools {
+		specs = append(specs, t.spec)
+	}
+	return specs
+}
+
+// run ejecuta una llamada del modelo; los errores vuelven al modelo como resultado para que pueda corregirse
+func (s *toolScope) run(ctx context.Context, call bedrock.ToolCall) bedrock.ToolResult {
+	result := bedrock.ToolResult{ToolCallID: call.ID}
+
+	var handler toolHandler
+	for _, t := range chatTools {
+		if t.spec.Name == call.Name {
+			handler = t.run
+			break
+		}
+	}
+	if handler == nil {
+		result.Content, result.IsError = fmt.Sprintf("herramienta desconocida: %s", call.Name), true
+		return result
+	}
+
+	var input toolInput
+	if len(call.Input) > 0 {
+		if err := json.Unmarshal(call.Input, &input); err != nil {
+			result.Content, result.IsError = fmt.Sprintf("argumentos inválidos: %v", err), true
+			return result
+		}
+	}
+
+	value, err := handler(ctx, s, input)
+	if err != nil {
+		result.Content, result.IsError = err.Error(), true
+		return result
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		result.Content, result.IsError = err.Error(), true
+		return result
+	}
+	result.Content = string(raw)
+	if len(result.Content) > maxToolResultChars {
+		// se corta en el inicio de una runa para no dejar UTF-8 inválido
+		cut := maxToolResultChars
+		for cut > 0 && !utf8.RuneStart(result.Content[cut]) {
+			cut--
+		}
+		result.Content = result.Content[:cut] + "…(recortado)"
+	}
+	return result
+}
+
+func listProducts(_ context.Context, s *toolScope, input toolInput) (any, error) {
+	page, size := pagination(input)
+	return s.sources.Stock.List(s.clientAccountId, page, size)
+}
+
+func lowStockProducts(_ context.Context, s *toolScope, input toolInput) (any, error) {
+	threshold := 5
+	if input.Threshold != nil {
+		threshold = *input.Threshold
+	}
+	return s.sources.Stock.LowStock(s.clientAccountId, threshold, limit(input, 20))
+}
+
+func getProduct(ctx context.Context, s *toolScope, input toolInput) (any, error) {
+	productId, err := s.ownProduct(ctx, input.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	return s.sources.Stock.Get(productId)
+}
+
+func listProductMovements(ctx context.Context, s *toolScope, input toolInput) (any, error) {
+	productId, err := s.ownProduct(ctx, input.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	page, size := pagination(input)
+	return s.sources.Movements.List(productId, page, size)
+}
+
+func topProducts(_ context.Context, s *toolScope, input toolInput) (any, error) {
+	clienteID, err := s.dimCliente()
+	if err != nil {
+		return nil, err
+	}
+	filter, err := dateFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	return s.sources.Dashboard.GetTopProducts(clienteID, limit(input, 10), filter)
+}
+
+func movementsSummary(_ context.Context, s *toolScope, _ toolInput) (any, error) {
+	clienteID, err := s.dimCliente()
+	if err != nil {
+		return nil, err
+	}
+	return s.sources.Dashboard.GetSummaryForClient(clienteID)
+}
+
+func movementsOverTime(_ context.Context, s *toolScope, input toolInput) (any, error) {
+	clienteID, err := s.dimCliente()
+	if err != nil {
+		return nil, err
+	}
+	filter, err := dateFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	filter.Period = input.Period
+	return s.sources.Dashboard.GetMovementOverTime(clienteID, filter)
+}
+
+func movementsByType(_ context.Context, s *toolScope, _ toolInput) (any, error) {
+	clienteID, err := s.dimCliente()
+	if err != nil {
+		return nil, err
+	}
+	return s.sources.Dashboard.GetMovementsByTypeForClient(clienteID)
+}
+
+func listRequests(ctx context.Context, s *toolScope, input toolInput) (any, error) {
+	page, size := pagination(input)
+	return s.sources.Requests.List(ctx, s.clientAccountId, input.Folio, page, size)
+}
+
+func getRequest(ctx context.Context, s *toolScope, input toolInput) (any, error) {
+	requestId, err := uuid.Parse(input.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("request_id inválido: %q", input.RequestID)
+	}
+	found, err := s.sources.Requests.Get(ctx, s.clientAccountId, requestId)
+	if err != nil {
+		return nil, fmt.Errorf("solicitud %s no encontrada", requestId)
+	}
+	return found, nil
+}
+
+func supplierSummary(ctx context.Context, s *toolScope, input toolInput) (any, error) {
+	filter, err := dateFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	from, to := filter.Start, filter.End
+	if from.IsZero() {
+		from = time.Now().AddDate(0, 0, -30)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	} else {
+		// end es inclusivo
+		to = to.AddDate(0, 0, 1)
+	}
+	return s.sources.Requests.SupplierSummary(ctx, s.clientAccountId, from, to, limit(input, 10))
+}
+
+// ownProduct valida que el producto exista y sea de la cuenta de la sesión
+func (s *toolScope) ownProduct(ctx context.Context, raw string) (uuid.UUID, error) {
+	productId, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("product_id inválido: %q", raw)
+	}
+	var count int64
+	err = s.db.WithContext(ctx).Model(&models.Product{}).
+		Where("id = ? AND client_account_id = ?", productId, s.clientAccountId).
+		Count(&count).Error
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if count == 0 {
+		return uuid.Nil, fmt.Errorf("producto %s no encontrado", productId)
+	}
+	return productId, nil
+}
+
+func (s *toolScope) dimCliente() (int, error) {
+	clienteID, err := s.sources.Dashboard.GetDimClienteID(s.clientAccountId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errors.New("la cuenta todavía no tiene movimientos en el dashboard")
+	}
+	return clienteID, err
+}
+
+func dateFilter(input toolInput) (dashboard.Filter, error) {
+	var filter dashboard.Filter
+	var err error
+	if input.Start != "" {
+		if filter.Start, err = time.Parse(dateLayout, input.Start); err != nil {
+			return filter, fmt.Errorf("start inválido %q, se espera AAAA-MM-DD", input.Start)
+		}
+	}
+	if input.End != "" {
+		if filter.End, err = time.Parse(dateLayout, input.End); err != nil {
+			return filter, fmt.Errorf("end inválido %q, se espera AAAA-MM-DD", input.End)
+		}
+	}
+	return filter, nil
+}
+
+func pagination(input toolInput) (int, int) {
+	page, size := input.Page, input.Size
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 50 {
+		size = 20
+	}
+	return page, size
+}
+
+func limit(input toolInput, def int) int {
+	if input.Limit < 1 || input.Limit > 50 {
+		return def
+	}
+	return input.Limit
+}
@@ -0,0 +1,295 @@
+package dashboard
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"gorm.io/gorm"
+)
+
+// Filter acota las consultas del dashboard; los campos en cero no filtran.
+// Period es "week" o "month" (por defecto) y ProductoID es el id de dim_producto.
+type Filter struct {
+	Start      time.Time
+	End        time.Time
+	Period     string
+	ProductoID int
+}
+
+func (f Filter) hasRange() bool {
+	return !f.Start.IsZero() && !f.End.IsZero()
+}
+
+// DashboardService consulta los agregados del esquema estrella; clienteID es el id de dim_cliente
+type DashboardService interface {
+	GetDimClienteID(clientAccountId uuid.UUID) (int, error)
+	GetMovementOverTime(clienteID int, filter Filter) ([]MovementOverTime, error)
+	GetTopProducts(clienteID int, limit int, filter Filter) ([]TopProduct, error)
+	GetStockTrend(clienteID int) ([]StockTrend, error)
+	GetSummaryForClient(clienteID int) (SummaryForClient, error)
+	GetMovementsByTypeForClient(clienteID int) ([]MovementsByType, error)
+	GetMovementsByUserForClient(clienteID int) ([]MovementsByUser, error)
+	GetProductActivity(clienteID int, clientAccountId uuid.UUID, filter Filter, limit int) ([]ProductActivity, error)
+	GetRequestActivity(clienteID int, filter Filter, limit int) ([]RequestActivity, error)
+}
+
+type dashboardService struct {
+	db *gorm.DB
+}
+
+func NewDashboardService(db *gorm.DB) DashboardService {
+	return &dashboardService{db: db}
+}
+
+type MovementOverTime struct {
+	Periodo        time.Time `json:"periodo"`
+	Mes            string    `json:"mes"`
+	Ingresos       int64     `json:"ingresos"`
+	Egresos        int64     `json:"egresos"`
+	StockAcumulado int64     `json:"stock_acumulado"`
+}
+
+func (d dashboardService) GetMovementOverTime(clientID int, filter Filter) ([]MovementOverTime, error) {
+	var results []MovementOverTime
+
+	period := filter.Period
+	if period != "week" {
+		period = "month"
+	}
+
+	// Construir WHERE dinámico según parámetros opcionales
+	whereClause := " WHERE f.cliente_id = ?"
+	args := []interface{}{period, clientID}
+
+	if filter.hasRange() {
+		whereClause += " AND df.fecha BETWEEN ? AND ?"
+		args = append(args, filter.Start, filter.End)
+	}
+	if filter.ProductoID != 0 {
+		whereClause += " AND f.producto_id = ?"
+		args = append(args, filter.ProductoID)
+	}
+
+	query := `
+WITH base AS (
+    SELECT
+        CASE WHEN ? = 'week' THEN date_trunc('week', df.fecha) ELSE date_trunc('month', df.fecha) END AS periodo,
+        f.tipo_movimiento_id,
+        f.cantidad,
+        (f.cantidad * f.signo) AS movimiento
+    FROM fact_product_movement f
+    JOIN dim_fecha df ON f.fecha_key = df.fecha_key` + whereClause + `
+),
+resumen AS (
+    SELECT
+        periodo,
+        SUM(CASE WHEN tipo_movimiento_id = 7 THEN cantidad ELSE 0 END) AS ingresos,
+        SUM(CASE WHEN tipo_movimiento_id = 8 THEN cantidad ELSE 0 END) AS egresos,
+        SUM(movimiento) AS movimiento_del_periodo
+    FROM base
+    GROUP BY periodo
+)
+SELECT
+    periodo,
+    (ARRAY['enero','febrero','marzo','abril','mayo','junio','julio','agosto','septiembre','octubre','noviembre','diciembre'])[date_part('month', periodo)::int] AS mes,
+    ingresos,
+    egresos,
+    SUM(movimiento_del_periodo) OVER (ORDER BY periodo) AS stock_acumulado
+FROM resumen
+ORDER BY periodo;
+`
+
+	err := d.db.Raw(query, args...).Scan(&results).Error
+
+	return results, err
+}
+
+type TopProduct struct {
+	NombreProducto string `json:"nombre_producto"`
+	Egresos        int64  `json:"egresos"`
+	Ingresos       int64  `json:"ingresos"`
+	Total          int64  `json:"total"`
+}
+
+func (d dashboardService) GetTopProducts(clientID int, limit int, filter Filter) ([]TopProduct, error) {
+	var results []TopProduct
+
+	query := d.db.Table("fact_product_movement f").
+		Select(`dp.id, dp.nombre AS nombre_producto,
+		SUM(CASE WHEN f.tipo_movimiento_id = 8 THEN f.cantidad ELSE 0 END) AS egresos,
+		SUM(CASE WHEN f.tipo_movimiento_id = 7 THEN f.cantidad ELSE 0 END) AS ingresos,
+		SUM(f.cantidad) AS total`).
+		Joins("JOIN dim_producto dp ON f.producto_id = dp.id").
+		Joins("JOIN dim_fecha df ON df.fecha_key = f.fecha_key").
+		Where("f.cliente_id = ?", clientID)
+
+	if filter.hasRange() {
+		query = query.Where("df.fecha BETWEEN ? AND ?", filter.Start, filter.End)
+	}
+
+	err := query.
+		Group("dp.nombre, dp.id").
+		Order("total DESC").
+		Limit(limit).
+		Scan(&results).Error
+
+	return results, err
+}
+
+type StockTrend struct {
+	Fecha          time.Time `json:"fecha"`
+	StockAcumulado int64     `json:"stock_acumulado"`
+}
+
+func (d dashboardService) GetStockTrend(clientID int) ([]StockTrend, error) {
+	var results []StockTrend
+	query := `
+        SELECT df.fecha AS fecha,
+               SUM(f.cantidad * f.signo) OVER (ORDER BY df.fecha) AS stock_acumulado
+        FROM fact_product_movement f
+        JOIN dim_fecha df ON f.fecha_key = df.fecha_key
+        WHERE f.cliente_id = ?
+        ORDER BY df.fecha
+    `
+
+	err := d.db.Raw(query, clientID).Scan(&results).Error
+	return results, err
+}
+
+type SummaryForClient struct {
+	Ingresos int64 `json:"ingresos"`
+	Egresos  int64 `json:"egresos"`
+}
+
+func (d dashboardService) GetSummaryForClient(clientID int) (SummaryForClient, error) {
+	var result SummaryForClient
+	err := d.db.Table("fact_product_movement f").
+		Select(`
+            SUM(CASE WHEN f.signo = 1 THEN f.cantidad ELSE 0 END) AS ingresos,
+            SUM(CASE WHEN f.signo = -1 THEN f.cantidad ELSE 0 END) AS egresos
+        `).
+		Where("f.cliente_id = ?", clientID).
+		Scan(&result).Error
+	return result, err
+}
+
+type MovementsByType struct {
+	Tipo  string `json:"tipo"`
+	Total int64  `json:"total"`
+}
+
+func (d dashboardService) GetMovementsByTypeForClient(clientID int) ([]MovementsByType, error) {
+	var results []MovementsByType
+
+	err := d.db.Table("fact_product_movement f").
+		Select("dtm.nombre AS tipo, SUM(f.cantidad) AS total").
+		Joins("JOIN dim_tipo_movimiento dtm ON f.tipo_movimiento_id = dtm.id").
+		Where("f.cliente_id = ?", clientID).
+		Group("dtm.nombre").
+		Scan(&results).Error
+
+	return results, err
+}
+
+type MovementsByUser struct {
+	Usuario     string `json:"usuario"`
+	Movimientos int64  `json:"movimientos"`
+}
+
+func (d dashboardService) GetMovementsByUserForClient(clientID int) ([]MovementsByUser, error) {
+	var results []MovementsByUser
+
+	err := d.db.Table("fact_product_movement f").
+		Select("du.nombre AS usuario, COUNT(f.id) AS movimientos").
+		Joins("JOIN dim_usuario du ON f.usuario_id = du.id").
+		Where("f.cliente_id = ?", clientID).
+		Group("du.nombre").
+		Order("movimientos DESC").
+		Scan(&results).Error
+
+	return results, err
+}
+
+func (d dashboardService) GetDimClienteID(uuid uuid.UUID) (int, error) {
+	var cliente models.DimCliente
+	// Busca el registro cuyo cliente_uuid coincida y trae el primero
+	err := d.db.Where("cliente_uuid = ?", uuid).First(&cliente).Error
+	if err != nil {
+		return 0, err
+	}
+	return cliente.ID, nil
+}
+
+// ProductActivity es el stock de un producto del cliente y lo que se movió en el período
+type ProductActivity struct {
+	ProductoUUID     uuid.UUID  `json:"product_id"`
+	Nombre           string     `json:"nombre"`
+	Stock            int64      `json:"stock"`
+	Ingresos         int64      `json:"ingresos"`
+	Egresos          int64      `json:"egresos"`
+	UltimoMovimiento *time.Time `json:"ultimo_movimiento,omitempty"`
+}
+
+// GetProductActivity lista los productos del cliente con sus ingresos y egresos del período, los de más movimiento primero
+func (d dashboardService) GetProductActivity(clientID int, clientAccountId uuid.UUID, filter Filter, limit int) ([]ProductActivity, error) {
+	var results []ProductActivity
+
+	movements := d.db.Table("fact_product_movement f").
+		Select(`f.producto_id,
+		SUM(CASE WHEN f.signo = 1 THEN f.cantidad ELSE 0 END) AS ingresos,
+		SUM(CASE WHEN f.signo = -1 THEN f.cantidad ELSE 0 END) AS egresos,
+		MAX(df.fecha) AS ultimo_movimiento`).
+		Joins("JOIN dim_fecha df ON df.fecha_key = f.fecha_key").
+		Where("f.cliente_id = ?", clientID).
+		Group("f.producto_id")
+	if filter.hasRange() {
+		movements = movements.Where("df.fecha BETWEEN ? AND ?", filter.Start, filter.End)
+	}
+
+	err := d.db.Table("dim_producto dp").
+		Select(`dp.producto_uuid, dp.nombre, dp.stock,
+		COALESCE(m.ingresos, 0) AS ingresos, COALESCE(m.egresos, 0) AS egresos, m.ultimo_movimiento`).
+		Joins("LEFT JOIN (?) m ON m.producto_id = dp.id", movements).
+		Where("dp.cliente_uuid = ?", clientAccountId).
+		Order("COALESCE(m.ingresos, 0) + COALESCE(m.egresos, 0) DESC, dp.nombre").
+		Limit(limit).
+		Scan(&results).Error
+
+	return results, err
+}
+
+// RequestActivity son las unidades que movió una solicitud y qué productos tocó
+type RequestActivity struct {
+	SolicitudUUID uuid.UUID `json:"request_id"`
+	Fecha         time.Time `json:"fecha"`
+	Ingresos      int64     `json:"ingresos"`
+	Egresos       int64     `json:"egresos"`
+	Productos     string    `json:"productos"`
+}
+
+// GetRequestActivity lista las solicitudes del cliente con movimientos en el período, las más recientes primero
+func (d dashboardService) GetRequestActivity(clientID int, filter Filter, limit int) ([]RequestActivity, error) {
+	var results []RequestActivity
+
+	query := d.db.Table("fact_product_movement f").
+		Select(`ds.solicitud_uuid, MIN(df.fecha) AS fecha,
+		SUM(CASE WHEN f.signo = 1 THEN f.cantidad ELSE 0 END) AS ingresos,
+		SUM(CASE WHEN f.signo = -1 THEN f.cantidad ELSE 0 END) AS egresos,
+		STRING_AGG(DISTINCT dp.nombre, ', ') AS productos`).
+		Joins("JOIN dim_solicitud ds ON ds.id = f.solicitud_id").
+		Joins("JOIN dim_producto dp ON dp.id = f.producto_id").
+		Joins("JOIN dim_fecha df ON df.fecha_key = f.fecha_key").
+		Where("f.cliente_id = ?", clientID)
+	if filter.hasRange() {
+		query = query.Where("df.fecha BETWEEN ? AND ?", filter.Start, filter.End)
+	}
+
+	err := query.
+		Group("ds.solicitud_uuid").
+		Order("fecha DESC").
+		Limit(limit).
+		Scan(&results).Error
+
+	return results, err
+}
@@ -39,6 +39,8 @@ type RequestProcessEvent struct {
 	RequestID       uuid.UUID `json:"request_id"`
 	ClientAccountId uuid.UUID `json:"client_account_id"`
 	TypeIngress     int       `json:"type_ingress"`
+	Model           string    `json:"model,omitempty"`
+	PromptVersion   string    `json:"prompt_version,omitempty"`
 }
 
 type ProductEvent struct {
@@ -46,6 +48,7 @@ type ProductEvent struct {
 	ProductoID      string    `json:"producto_id"`
 	NombreProducto  string    `json:"nombre_producto"`
 	ClienteID       string    `json:"cliente_id"`
+	MovimientoID    string    `json:"movimiento_id,omitempty"`
 	Cantidad        int       `json:"cantidad"`
 	Signo           int       `json:"signo"` // 1 ingreso, -1 egreso
 	Fecha           time.Time `json:"fecha"`
@@ -53,3 +56,28 @@ type ProductEvent struct {
 	StatusSolicitud string    `json:"status_solicitud"`
 	TipoMovimiento  string    `json:"tipo_movimiento"`
 }
+
+// ---- Ajuste de un movimiento ya cargado en el esquema estrella ----
+type ProductAdjustEvent struct {
+	BaseEvent
+	ProductoID   string `json:"producto_id"`
+	MovimientoID string `json:"movimiento_id"`
+	DeltaStock   int    `json:"delta_stock"`
+	Cantidad     int    `json:"cantidad"` // cantidad final del movimiento
+
+	// Con ProductoDestinoID el movimiento pasa a ese producto, que recibe DeltaStockDestino;
+	// NombreDestino y ClienteID permiten crearlo en la dimensión si todavía no está
+	ProductoDestinoID string `json:"producto_destino_id,omitempty"`
+	DeltaStockDestino int    `json:"delta_stock_destino,omitempty"`
+	NombreDestino     string `json:"nombre_destino,omitempty"`
+	ClienteID         string `json:"cliente_id,omitempty"`
+}
+
+// ---- Alta o cambio de un producto del catálogo, sin movimiento de stock ----
+type ProductCatalogEvent struct {
+	BaseEvent
+	ProductoID     string `json:"producto_id"`
+	NombreProducto string `json:"nombre_producto"`
+	ClienteID      string `json:"cliente_id"`
+	Status         string `json:"status"`
+}
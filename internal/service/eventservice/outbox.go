@@ -0,0 +1,166 @@
+package eventservice
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	outboxPollInterval = 2 * time.Second
+	outboxBatchSize    = 50
+	outboxConfirmWait  = 5 * time.Second
+	outboxMaxBackoff   = 5 * time.Minute
+	// outboxClaimLease cubre la publicación de un lote completo (outboxBatchSize * outboxConfirmWait)
+	outboxClaimLease = 5 * time.Minute
+)
+
+// Outbox publica los eventos guardados en outbox_event y permite revisar los que no salen.
+type Outbox struct {
+	db        *gorm.DB
+	publisher *MQPublisher
+}
+
+func NewOutbox(db *gorm.DB, publisher *MQPublisher) *Outbox {
+	return &Outbox{db: db, publisher: publisher}
+}
+
+// Run ejecuta el relay hasta que se cancele el contexto.
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := o.relayBatch(ctx)
+				if err != nil {
+					log.Printf("❌ outbox relay: %v", err)
+					break
+				}
+				if n < outboxBatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// relayBatch publica un lote de eventos reclamados. La transacción del reclamo ya terminó, así que ninguna
+// fila queda bloqueada mientras se espera al broker; si la instancia cae antes de marcar un evento, este vuelve
+// a salir al vencer el reclamo y los consumidores descartan el duplicado.
+func (o *Outbox) relayBatch(ctx context.Context) (int, error) {
+	events, err := o.claimBatch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, evt := range events {
+		if err := o.publish(ctx, evt); err != nil {
+			attempts := evt.Attempts + 1
+			log.Printf("❌ outbox evento %s rk=%s intento %d: %v", evt.ID, evt.RoutingKey, attempts, err)
+
+			err = o.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", evt.ID).Updates(map[string]any{
+				"attempts":        attempts,
+				"last_error":      err.Error(),
+				"next_attempt_at": time.Now().UTC().Add(backoff(attempts)),
+			}).Error
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		now := time.Now().UTC()
+		err := o.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+			Where("id = ? AND status = ?", evt.ID, models.OutboxStatusPending).
+			Updates(map[string]any{
+				"status":     models.OutboxStatusSent,
+				"attempts":   evt.Attempts + 1,
+				"last_error": nil,
+				"sent_at":    now,
+			}).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(events), nil
+}
+
+// claimBatch toma un lote de eventos vencidos (SKIP LOCKED permite varias instancias) y corre su next_attempt_at
+// en outboxClaimLease, para que otra instancia no los tome mientras se publican
+func (o *Outbox) claimBatch(ctx context.Context) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+
+	err := o.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusPending, now).
+			Order("created_at").
+			Limit(outboxBatchSize).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(events))
+		for _, evt := range events {
+			ids = append(ids, evt.ID)
+		}
+		return tx.Model(&models.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(outboxClaimLease)).Error
+	})
+
+	return events, err
+}
+
+func (o *Outbox) publish(ctx context.Context, evt models.OutboxEvent) error {
+	var headers map[string]any
+	if evt.Headers != "" {
+		if err := json.Unmarshal([]byte(evt.Headers), &headers); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, outboxConfirmWait)
+	defer cancel()
+
+	return o.publisher.publishConfirmed(ctx, evt.ID.String(), evt.RoutingKey, []byte(evt.Payload), headers)
+}
+
+// backoff exponencial desde 2s, acotado a outboxMaxBackoff
+func backoff(attempts int) time.Duration {
+	d := 2 * time.Second
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return d
+}
+
+// ListStuck devuelve los eventos pendientes que ya fallaron minAttempts veces o llevan más de olderThan sin salir.
+func (o *Outbox) ListStuck(ctx context.Context, minAttempts int, olderThan time.Duration, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+
+	err := o.db.WithContext(ctx).
+		Where("status = ?", models.OutboxStatusPending).
+		Where("attempts >= ? OR created_at <= ?", minAttempts, time.Now().UTC().Add(-olderThan)).
+		Order("created_at").
+		Limit(limit).
+		Find(&events).Error
+
+	return events, err
+}
@@ -2,36 +2,79 @@ package eventservice
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/wagslane/go-rabbitmq"
 )
 
-func (p *MQPublisher) publishJSON(routingKey string, msg any, headers map[string]any, ctx context.Context) error {
-	body, err := json.Marshal(msg)
-	if err != nil {
-		return err
+// publishConfirmed publica el cuerpo ya serializado y espera la confirmación del broker.
+func (p *MQPublisher) publishConfirmed(ctx context.Context, messageId string, routingKey string, body []byte, headers map[string]any) error {
+	if p.pub == nil {
+		return fmt.Errorf("publisher MQ no disponible")
 	}
 	t0 := time.Now()
 
-	err = p.pub.PublishWithContext(
+	if headers == nil {
+		headers = map[string]any{}
+	}
+	headers["x-delay-sla"] = 2000 // opcional para logging
+
+	confirms, err := p.pub.PublishWithDeferredConfirmWithContext(
 		ctx, body, []string{routingKey},
 		rabbitmq.WithPublishOptionsExchange(ExchangeName),
 		rabbitmq.WithPublishOptionsPersistentDelivery,
 		rabbitmq.WithPublishOptionsContentType("application/json"),
+		rabbitmq.WithPublishOptionsMessageID(messageId),
 		rabbitmq.WithPublishOptionsHeaders(headers),
-		rabbitmq.WithPublishOptionsMandatory, // útil para detectar no-route
-		rabbitmq.WithPublishOptionsHeaders(map[string]any{
-			"x-delay-sla": 2000, // opcional para logging
-		}),
-		// 👇 TTL por mensaje (en ms)
-		rabbitmq.WithPublishOptionsExpiration("2000"),
+		// sin TTL por mensaje: lo que espera en una cola durable no se pierde aunque el consumidor vaya lento
 	)
 	if err != nil {
 		log.Printf("❌ publish err rk=%s err=%v elapsed=%s", routingKey, err, time.Since(t0))
-		return nil
+		return err
+	}
+
+	return waitConfirms(ctx, routingKey, confirms)
+}
+
+// Park deja la entrega tal cual en la cola de fallidos, publicando por el exchange por defecto
+func (p *MQPublisher) Park(ctx context.Context, queue RetryQueue, d rabbitmq.Delivery) error {
+	if p.pub == nil {
+		return fmt.Errorf("publisher MQ no disponible")
+	}
+
+	headers := map[string]any{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	confirms, err := p.pub.PublishWithDeferredConfirmWithContext(
+		ctx, d.Body, []string{queue.FailedName()},
+		rabbitmq.WithPublishOptionsExchange(""),
+		rabbitmq.WithPublishOptionsPersistentDelivery,
+		rabbitmq.WithPublishOptionsContentType(d.ContentType),
+		rabbitmq.WithPublishOptionsMessageID(d.MessageId),
+		rabbitmq.WithPublishOptionsHeaders(headers),
+	)
+	if err != nil {
+		return err
+	}
+	return waitConfirms(ctx, queue.FailedName(), confirms)
+}
+
+func waitConfirms(ctx context.Context, routingKey string, confirms rabbitmq.PublisherConfirmation) error {
+	for _, confirm := range confirms {
+		if confirm == nil {
+			continue
+		}
+		acked, err := confirm.WaitContext(ctx)
+		if err != nil {
+			return fmt.Errorf("esperando confirmación rk=%s: %w", routingKey, err)
+		}
+		if !acked {
+			return fmt.Errorf("el broker rechazó el mensaje rk=%s", routingKey)
+		}
 	}
 	return nil
 }
@@ -1,22 +1,26 @@
 package eventservice
 
 import (
-	"context"
+	"encoding/json"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/models"
 	"github.com/wagslane/go-rabbitmq"
+	"gorm.io/gorm"
 )
 
 type EventPublisher interface {
-	PublishRequest(e RequestProcessEvent) error
-	PublishMovements(e MovementsEvent) error
+	EnqueueRequest(tx *gorm.DB, e RequestProcessEvent) error
+	EnqueueMovements(tx *gorm.DB, e MovementsEvent) error
 }
 
 const MovementTopic = "movement.generated"
 const RequestTopic = "Request.process.prod"
 const EtlProduct = "Product.etl.prod"
+const EtlAdjust = "Product.etl.adjust.prod"
+const EtlCatalog = "Product.etl.catalog.prod"
 
 type MQPublisher struct {
 	pub           *rabbitmq.Publisher
@@ -27,56 +31,79 @@ func NewMQPublisher(pub *rabbitmq.Publisher, urlConnection string) *MQPublisher
 	return &MQPublisher{pub: pub, urlConnection: urlConnection}
 }
 
-func (p *MQPublisher) PublishRequest(e RequestProcessEvent, ctx context.Context) error {
-	if e.EventID == "" {
-		e.EventID = newUUID()
-	}
-	if e.EventType == "" {
-		e.EventType = "document"
-	}
-	if e.Version == "" {
-		e.Version = "1"
-	}
-	if e.OccurredAt.IsZero() {
-		e.OccurredAt = time.Now().UTC()
-	}
+// Los eventos no se publican directo: se guardan en outbox_event dentro de la transacción
+// del llamador y el OutboxRelay los envía al broker.
 
-	log.Println("Publishing request event:", e)
-	return p.publishJSON(RequestTopic, e, map[string]any{
-		"type":          "document",
-		"version":       e.Version,
-		"correlationId": e.CorrelationID,
-	}, ctx)
+func (p *MQPublisher) EnqueueRequest(tx *gorm.DB, e RequestProcessEvent) error {
+	e.BaseEvent = withDefaults(e.BaseEvent, "document")
+
+	log.Println("Enqueue request event:", e)
+	return p.enqueue(tx, RequestTopic, e.BaseEvent, e)
 }
 
-func (p *MQPublisher) PublishMovements(e MovementsEvent) error {
-	if e.EventID == "" {
-		e.EventID = newUUID()
-	}
-	if e.EventType == "" {
-		e.EventType = "document"
+func (p *MQPublisher) EnqueueMovements(tx *gorm.DB, e MovementsEvent) error {
+	e.BaseEvent = withDefaults(e.BaseEvent, "document")
+
+	log.Println("Enqueue movement event:", e)
+	return p.enqueue(tx, MovementTopic, e.BaseEvent, e)
+}
+
+func (p *MQPublisher) EnqueueProductEtl(tx *gorm.DB, e ProductEvent) error {
+	e.BaseEvent = withDefaults(e.BaseEvent, "document")
+
+	log.Println("Enqueue etl:", e)
+	return p.enqueue(tx, EtlProduct, e.BaseEvent, e)
+}
+
+func (p *MQPublisher) EnqueueProductAdjust(tx *gorm.DB, e ProductAdjustEvent) error {
+	e.BaseEvent = withDefaults(e.BaseEvent, "adjust")
+
+	log.Println("Enqueue etl adjust:", e)
+	return p.enqueue(tx, EtlAdjust, e.BaseEvent, e)
+}
+
+func (p *MQPublisher) EnqueueProductCatalog(tx *gorm.DB, e ProductCatalogEvent) error {
+	e.BaseEvent = withDefaults(e.BaseEvent, "catalog")
+
+	log.Println("Enqueue etl catalog:", e)
+	return p.enqueue(tx, EtlCatalog, e.BaseEvent, e)
+}
+
+func (p *MQPublisher) enqueue(tx *gorm.DB, routingKey string, base BaseEvent, msg any) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
 	}
-	if e.Version == "" {
-		e.Version = "1"
+	headers, err := json.Marshal(map[string]any{
+		"type":          base.EventType,
+		"version":       base.Version,
+		"correlationId": base.CorrelationID,
+	})
+	if err != nil {
+		return err
 	}
-	if e.OccurredAt.IsZero() {
-		e.OccurredAt = time.Now().UTC()
+
+	id, err := uuid.Parse(base.EventID)
+	if err != nil {
+		id = uuid.New()
 	}
 
-	log.Println("Publishing movement event:", e)
-	return p.publishJSON(MovementTopic, e, map[string]any{
-		"type":          "document",
-		"version":       e.Version,
-		"correlationId": e.CorrelationID,
-	}, context.Background())
+	return tx.Create(&models.OutboxEvent{
+		ID:            id,
+		RoutingKey:    routingKey,
+		Payload:       string(payload),
+		Headers:       string(headers),
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now().UTC(),
+	}).Error
 }
 
-func (p *MQPublisher) PublishProductEtl(e ProductEvent) error {
+func withDefaults(e BaseEvent, eventType string) BaseEvent {
 	if e.EventID == "" {
 		e.EventID = newUUID()
 	}
 	if e.EventType == "" {
-		e.EventType = "document"
+		e.EventType = eventType
 	}
 	if e.Version == "" {
 		e.Version = "1"
@@ -84,13 +111,7 @@ func (p *MQPublisher) PublishProductEtl(e ProductEvent) error {
 	if e.OccurredAt.IsZero() {
 		e.OccurredAt = time.Now().UTC()
 	}
-
-	log.Println("publish  etl:", e)
-	return p.publishJSON(EtlProduct, e, map[string]any{
-		"type":          "document",
-		"version":       e.Version,
-		"correlationId": e.CorrelationID,
-	}, context.Background())
+	return e
 }
 
 func newUUID() string { return uuid.New().String() }
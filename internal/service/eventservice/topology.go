@@ -1,6 +1,11 @@
 package eventservice
 
-import amqp "github.com/rabbitmq/amqp091-go"
+import (
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
 
 const (
 	ExchangeKindTopic = "topic"
@@ -18,3 +23,65 @@ func EnsureTopology(ch *amqp.Channel) error {
 		nil,
 	)
 }
+
+// RetryQueue es una cola durable con reintento diferido: lo que el consumidor rechaza sin reencolar pasa por
+// el exchange por defecto a <cola>.retry, que lo devuelve a la cola al vencer su TTL. Lo que agota MaxAttempts
+// se deja en <cola>.failed para revisarlo a mano.
+type RetryQueue struct {
+	Name        string
+	RoutingKey  string
+	Delay       time.Duration
+	MaxAttempts int
+}
+
+func (q RetryQueue) RetryName() string  { return q.Name + ".retry" }
+func (q RetryQueue) FailedName() string { return q.Name + ".failed" }
+
+// QueueArgs son los argumentos de la cola principal, que declara el consumidor
+func (q RetryQueue) QueueArgs() map[string]any {
+	return map[string]any{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": q.RetryName(),
+	}
+}
+
+// Declare crea la cola de reintento y la de fallidos
+func (q RetryQueue) Declare(ch *amqp.Channel) error {
+	_, err := ch.QueueDeclare(q.RetryName(), true, false, false, false, amqp.Table{
+		"x-message-ttl":             q.Delay.Milliseconds(),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": q.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("declarando %s: %w", q.RetryName(), err)
+	}
+	if _, err := ch.QueueDeclare(q.FailedName(), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declarando %s: %w", q.FailedName(), err)
+	}
+	return nil
+}
+
+// Failures cuenta las veces que la entrega ya fue rechazada por la cola principal, según el header x-death
+func (q RetryQueue) Failures(d amqp.Delivery) int {
+	deaths, _ := d.Headers["x-death"].([]any)
+	for _, raw := range deaths {
+		death, ok := raw.(amqp.Table)
+		if !ok || death["queue"] != q.Name || death["reason"] != "rejected" {
+			continue
+		}
+		switch count := death["count"].(type) {
+		case int64:
+			return int(count)
+		case int32:
+			return int(count)
+		case int:
+			return count
+		}
+	}
+	return 0
+}
+
+// Exhausted indica si el fallo actual agota los intentos de la entrega
+func (q RetryQueue) Exhausted(d amqp.Delivery) bool {
+	return q.MaxAttempts > 0 && q.Failures(d)+1 >= q.MaxAttempts
+}
@@ -0,0 +1,137 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/textract"
+)
+
+// awsExtractor lee las tablas con Textract y las formatea como productos con el LLM configurado.
+// Con cache, ambos resultados se reutilizan para documentos con el mismo contenido.
+type awsExtractor struct {
+	textract textract.Service
+	llm      *bedrock.Service
+	cache    Cache
+}
+
+func NewAWSExtractor(textractSvc textract.Service, llm *bedrock.Service, cache Cache) Extractor {
+	return &awsExtractor{textract: textractSvc, llm: llm, cache: cache}
+}
+
+func (e *awsExtractor) Extract(ctx context.Context, doc Document, opts Options) (Result, error) {
+	analysis, fromCache, err := e.cachedAnalyze(ctx, doc, opts)
+	if err != nil {
+		return Result{}, fmt.Errorf("textract: %w", err)
+	}
+
+	log.Printf("Resultado de Textract para el documento %s:", doc.ID)
+
+	inputModel := textract.TablasToString(analysis.Tablas)
+
+	log.Printf("Input para Bedrock: %s", inputModel)
+
+	result := Result{TextractJobID: analysis.JobID}
+	if !fromCache {
+		result.TextractPages = analysis.Pages
+	}
+	if !analysis.Header.IsEmpty() {
+		result.Header = &analysis.Header
+	}
+
+	llm := e.llm.WithModel(opts.Model)
+	llmKey := llmCacheKey(doc.ContentHash, llm.Model(), opts.Prompt)
+
+	result.Model = llm.Model()
+
+	var cached llmCacheEntry
+	if hit := e.cacheGet(ctx, doc, llmKey, &cached); hit {
+		log.Printf("Resultado del LLM para el documento %s tomado de la caché", doc.ID)
+		result.RawOutput = cached.Output
+		result.Lines = tableSignals(cached.Products, analysis.Tablas)
+		return result, nil
+	}
+
+	resultBedrock, llmAttempts, err := llm.FormatProduct(ctx, inputModel, opts.Prompt)
+	result.LLMAttempts = llmAttempts
+	if len(llmAttempts) > 0 {
+		result.RawOutput = llmAttempts[len(llmAttempts)-1].Output
+	}
+	if err != nil {
+		log.Printf("Error al procesar con Bedrock: %v", err)
+		return result, fmt.Errorf("bedrock: %w", err)
+	}
+	log.Printf("Resultado de Bedrock para el documento %s: %+v", doc.ID, resultBedrock)
+
+	e.cachePut(ctx, doc, llmKey, llmCacheEntry{Products: *resultBedrock, Output: result.RawOutput})
+	result.Lines = tableSignals(*resultBedrock, analysis.Tablas)
+	return result, nil
+}
+
+// cachedAnalyze evita un nuevo análisis de Textract si ya se analizó un documento con el mismo contenido;
+// el bool indica si el análisis salió de la caché (y por lo tanto no se cobró)
+func (e *awsExtractor) cachedAnalyze(ctx context.Context, doc Document, opts Options) (*textract.Analysis, bool, error) {
+	key := textractCacheKey(doc.ContentHash)
+
+	var cached textract.Analysis
+	if hit := e.cacheGet(ctx, doc, key, &cached); hit {
+		log.Printf("Análisis de Textract para el documento %s tomado de la caché (job %s)", doc.ID, cached.JobID)
+		return &cached, true, nil
+	}
+
+	analysis, err := e.analyze(ctx, doc, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	e.cachePut(ctx, doc, key, analysis)
+	return analysis, false, nil
+}
+
+// cacheGet y cachePut no hacen nada sin caché o sin hash; un error de la caché solo se registra, no corta la extracción
+func (e *awsExtractor) cacheGet(ctx context.Context, doc Document, key string, v any) bool {
+	if e.cache == nil || doc.ContentHash == "" {
+		return false
+	}
+	hit, err := e.cache.Get(ctx, key, v)
+	if err != nil {
+		log.Printf("Error leyendo la caché %s: %v", key, err)
+		return false
+	}
+	return hit
+}
+
+func (e *awsExtractor) cachePut(ctx context.Context, doc Document, key string, v any) {
+	if e.cache == nil || doc.ContentHash == "" {
+		return
+	}
+	if err := e.cache.Put(ctx, key, v); err != nil {
+		log.Printf("Error guardando la caché %s: %v", key, err)
+	}
+}
+
+// analyze retoma el job del documento si ya existía; si no, o si expiró o había fallado, inicia uno nuevo
+// y lo informa antes de esperar para que un reinicio pueda retomarlo
+func (e *awsExtractor) analyze(ctx context.Context, doc Document, opts Options) (*textract.Analysis, error) {
+	if doc.TextractJobID != "" {
+		analysis, err := e.textract.WaitAnalysis(ctx, doc.TextractJobID)
+		if !errors.Is(err, textract.ErrJobNotFound) && !errors.As(err, new(*textract.JobFailedError)) {
+			return analysis, err
+		}
+		log.Printf("Job %s del documento %s no se puede retomar (%v), se inicia uno nuevo", doc.TextractJobID, doc.ID, err)
+	}
+
+	jobId, err := e.textract.StartAnalysis(ctx, doc.Bucket, doc.Key)
+	if err != nil {
+		return nil, err
+	}
+	if opts.JobStarted != nil {
+		if err := opts.JobStarted(doc.ID, jobId); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.textract.WaitAnalysis(ctx, jobId)
+}
@@ -0,0 +1,67 @@
+package extractor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Cache guarda resultados de extracción por clave; las claves se arman con el hash del contenido del documento
+type Cache interface {
+	Get(ctx context.Context, key string, v any) (bool, error)
+	Put(ctx context.Context, key string, v any) error
+}
+
+type dbCache struct {
+	db *gorm.DB
+}
+
+func NewDBCache(db *gorm.DB) Cache {
+	return &dbCache{db: db}
+}
+
+func (c *dbCache) Get(ctx context.Context, key string, v any) (bool, error) {
+	var entry models.ExtractionCache
+	err := c.db.WithContext(ctx).First(&entry, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(entry.Payload), v)
+}
+
+func (c *dbCache) Put(ctx context.Context, key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ExtractionCache{
+		Key:     key,
+		Payload: string(raw),
+	}).Error
+}
+
+// llmCacheEntry guarda la salida sin procesar junto a los productos para que un acierto de caché la registre igual
+type llmCacheEntry struct {
+	Products []bedrock.ProductResponse `json:"products"`
+	Output   string                    `json:"output"`
+}
+
+func textractCacheKey(contentHash string) string {
+	return "textract:" + contentHash
+}
+
+// llmCacheKey depende del modelo y del prompt completo: otro modelo u otra versión del prompt vuelven a invocar el LLM
+func llmCacheKey(contentHash string, model string, prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return "llm-output:" + contentHash + ":" + model + ":" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,79 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/config"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/textract"
+)
+
+// Document identifica un archivo de la solicitud ya cargado en el bucket.
+// TextractJobID es el job de un intento anterior, que se retoma en vez de iniciar otro.
+type Document struct {
+	ID            uuid.UUID
+	Bucket        string
+	Key           string
+	FileName      string
+	ContentType   string
+	TextractJobID string
+	// ContentHash es el SHA-256 del archivo; si viene, los resultados se buscan y guardan en la caché
+	ContentHash string
+}
+
+// Options ajusta una extracción: modelo y prompt ya resuelto para el intento,
+// y el mapeo de columnas guardado por el cliente para las planillas
+type Options struct {
+	Model   string
+	Prompt  string
+	Mapping *ColumnMapping
+
+	// JobStarted se llama apenas el backend inicia un análisis asíncrono, para guardar su id
+	JobStarted func(documentId uuid.UUID, jobId string) error
+}
+
+// Result son las líneas del documento; Header solo viene cuando el backend pudo leer la cabecera de la factura.
+// Si Extract falla, el Result parcial igual trae el job y las llamadas al LLM hechas, para registrarlas.
+type Result struct {
+	Lines         []Line
+	TextractJobID string
+	Header        *textract.InvoiceHeader
+	LLMAttempts   []bedrock.ExtractionAttempt
+	// Model y RawOutput son el modelo que formateó los productos y su salida sin procesar; vacíos sin LLM
+	Model     string
+	RawOutput string
+	// TextractPages son las páginas que cobró Textract en esta extracción; 0 si el análisis salió de la caché
+	TextractPages int
+}
+
+// Extractor convierte un documento en las líneas de producto que alimentan la solicitud
+type Extractor interface {
+	Extract(ctx context.Context, doc Document, opts Options) (Result, error)
+}
+
+// IsRetryable indica si la extracción falló por algo transitorio y la solicitud debe reintentarse, no rechazarse
+func IsRetryable(err error) bool {
+	return textract.IsRetryable(err)
+}
+
+// DocumentSource entrega el contenido de un documento por su key (S3Svc y LocalStorage lo implementan)
+type DocumentSource interface {
+	GetDocument(key string) (io.ReadCloser, error)
+}
+
+// New arma el extractor según la configuración; las planillas CSV/XLSX se leen siempre directo
+func New(cfg config.ExtractorConfig, source DocumentSource, llm *bedrock.Service, cache Cache) (Extractor, error) {
+	var documents Extractor
+	switch cfg.Backend {
+	case config.ExtractorBackendAWS:
+		documents = NewAWSExtractor(textract.NewTextractService(cfg.TextractRegion), llm, cache)
+	case config.ExtractorBackendLocal:
+		documents = NewLocalExtractor(source)
+	default:
+		return nil, fmt.Errorf("backend de extracción desconocido: %s", cfg.Backend)
+	}
+	return &byType{spreadsheet: NewSpreadsheetExtractor(source), documents: documents}, nil
+}
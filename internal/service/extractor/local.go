@@ -0,0 +1,74 @@
+package extractor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/utils"
+)
+
+// localExtractor es un extractor determinista para pruebas y desarrollo sin AWS.
+// Lee el documento como texto plano con una línea por producto:
+//
+//	nombre;cantidad;sku1,sku2
+//
+// Las líneas vacías, las que empiezan con # y las que no tienen una cantidad entera se ignoran.
+type localExtractor struct {
+	source DocumentSource
+}
+
+func NewLocalExtractor(source DocumentSource) Extractor {
+	return &localExtractor{source: source}
+}
+
+func (e *localExtractor) Extract(ctx context.Context, doc Document, _ Options) (Result, error) {
+	body, err := e.source.GetDocument(doc.Key)
+	if err != nil {
+		return Result{}, err
+	}
+	defer body.Close()
+
+	lines := make([]bedrock.ProductResponse, 0)
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		count, ok := utils.ParseQuantity(parts[1])
+		if !ok {
+			continue
+		}
+
+		product := bedrock.ProductResponse{
+			Name:  strings.TrimSpace(parts[0]),
+			Count: count,
+			SKUs:  []string{},
+		}
+		if len(parts) > 2 {
+			for _, sku := range strings.Split(parts[2], ",") {
+				if sku = strings.TrimSpace(sku); sku != "" {
+					product.SKUs = append(product.SKUs, sku)
+				}
+			}
+		}
+		lines = append(lines, product)
+	}
+	if err := scanner.Err(); err != nil {
+		return Result{}, fmt.Errorf("leyendo documento %s: %w", doc.Key, err)
+	}
+
+	return Result{Lines: exactLines(lines)}, nil
+}
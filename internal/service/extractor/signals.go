@@ -0,0 +1,150 @@
+package extractor
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/textract"
+	"github.com/stock-ahora/api-stock/internal/utils"
+)
+
+// Line es una línea extraída con las señales que usa el cálculo de confianza de la revisión
+type Line struct {
+	bedrock.ProductResponse
+	// OCRConfidence es la confianza (0-1) de Textract en la fila de origen; nil si el backend no lee con OCR
+	OCRConfidence *float64
+	// SkuSupplied indica que el SKU aparece en el documento y no fue generado por el modelo
+	SkuSupplied bool
+	// QuantityAmbiguous indica que la cantidad no aparece tal cual en una celda de la fila de origen
+	QuantityAmbiguous bool
+	// SourceRowFound indica que la línea se pudo ubicar en una fila del documento
+	SourceRowFound bool
+}
+
+// exactLines marca las líneas de un backend que lee el documento tal cual (planillas, texto plano)
+func exactLines(products []bedrock.ProductResponse) []Line {
+	lines := make([]Line, 0, len(products))
+	for _, p := range products {
+		lines = append(lines, Line{ProductResponse: p, SkuSupplied: len(p.SKUs) > 0, SourceRowFound: true})
+	}
+	return lines
+}
+
+// tableSignals ubica cada producto del modelo en la fila de Textract que mejor calza y toma de ahí sus señales
+func tableSignals(products []bedrock.ProductResponse, tablas []textract.TablaProducto) []Line {
+	var filas []textract.FilaProducto
+	for _, tabla := range tablas {
+		filas = append(filas, tabla.Filas...)
+	}
+
+	lines := make([]Line, 0, len(products))
+	for _, p := range products {
+		line := Line{ProductResponse: p, QuantityAmbiguous: true}
+
+		fila, ok := bestRow(p, filas)
+		if !ok {
+			lines = append(lines, line)
+			continue
+		}
+		line.SourceRowFound = true
+
+		rowText := compact(strings.Join(fila.Celdas, " "))
+		for _, sku := range p.SKUs {
+			if key := compact(sku); key != "" && strings.Contains(rowText, key) {
+				line.SkuSupplied = true
+				break
+			}
+		}
+
+		minConfidence := 100.0
+		quantityCells := 0
+		for i, celda := range fila.Celdas {
+			if i < len(fila.Confianzas) && celda != "" && float64(fila.Confianzas[i]) < minConfidence {
+				minConfidence = float64(fila.Confianzas[i])
+			}
+			if n, exact := cellNumber(celda); exact && n == p.Count {
+				quantityCells++
+			}
+		}
+		confidence := minConfidence / 100
+		line.OCRConfidence = &confidence
+		line.QuantityAmbiguous = quantityCells != 1
+
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// bestRow elige la fila que contiene alguno de los SKUs o, si no, la que comparte más palabras con el nombre
+func bestRow(p bedrock.ProductResponse, filas []textract.FilaProducto) (textract.FilaProducto, bool) {
+	nameWords := words(p.Name)
+
+	best, bestScore := -1, 0.0
+	for i, fila := range filas {
+		rowText := strings.Join(fila.Celdas, " ")
+		rowCompact := compact(rowText)
+
+		score := 0.0
+		for _, sku := range p.SKUs {
+			if key := compact(sku); len(key) >= 3 && strings.Contains(rowCompact, key) {
+				score = 2
+				break
+			}
+		}
+		if score == 0 && len(nameWords) > 0 {
+			rowWords := make(map[string]bool)
+			for _, w := range words(rowText) {
+				rowWords[w] = true
+			}
+			shared := 0
+			for _, w := range nameWords {
+				if rowWords[w] {
+					shared++
+				}
+			}
+			score = float64(shared) / float64(len(nameWords))
+		}
+
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 || bestScore < 0.5 {
+		return textract.FilaProducto{}, false
+	}
+	return filas[best], true
+}
+
+// cellNumber lee la celda como cantidad; exact es false si la celda trae decimales o más de un número
+func cellNumber(celda string) (int, bool) {
+	fields := strings.FieldsFunc(celda, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != ',' && r != '.'
+	})
+	if len(fields) != 1 {
+		return 0, false
+	}
+	return utils.ParseQuantity(strings.TrimRight(fields[0], ",."))
+}
+
+func compact(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, s)
+}
+
+func words(s string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if len([]rune(w)) >= 3 {
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package extractor
+
+import "testing"
+
+func TestCellNumber(t *testing.T) {
+	tests := []struct {
+		celda string
+		want  int
+		exact bool
+	}{
+		{"12", 12, true},
+		{"12 UN", 12, true},
+		{"1.234", 1234, true},
+		{"1.234,00", 1234, true},
+		{"1.234,5", 0, false},
+		{"2,5 kg", 0, false},
+		{"12.", 12, true},
+		{"3 x 4", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, exact := cellNumber(tt.celda)
+		if got != tt.want || exact != tt.exact {
+			t.Errorf("cellNumber(%q) = %d, %v; se esperaba %d, %v", tt.celda, got, exact, tt.want, tt.exact)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package extractor
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/utils"
+	"github.com/xuri/excelize/v2"
+)
+
+var ErrColumnsNotFound = errors.New("no se reconocieron las columnas de la planilla")
+
+// ColumnMapping indica por nombre de encabezado qué columnas traen nombre, cantidad y SKU.
+// Los campos vacíos se detectan con los encabezados habituales.
+type ColumnMapping struct {
+	Name     string
+	Quantity string
+	Sku      string
+}
+
+// encabezados reconocidos cuando el cliente no tiene un mapeo guardado
+var (
+	nameHeaders     = []string{"nombre", "producto", "descripcion", "detalle", "articulo", "name", "description", "product"}
+	quantityHeaders = []string{"cantidad", "cant", "unidades", "qty", "quantity", "units"}
+	skuHeaders      = []string{"sku", "codigo", "cod", "referencia", "ref", "code", "item"}
+)
+
+// IsSpreadsheet indica si el documento es una planilla que se lee directo, sin Textract ni LLM
+func IsSpreadsheet(doc Document) bool {
+	return isCSV(doc) || isXLSX(doc)
+}
+
+func isCSV(doc Document) bool {
+	ct := strings.ToLower(doc.ContentType)
+	return strings.HasPrefix(ct, "text/csv") ||
+		strings.EqualFold(filepath.Ext(doc.FileName), ".csv")
+}
+
+func isXLSX(doc Document) bool {
+	ct := strings.ToLower(doc.ContentType)
+	return strings.HasPrefix(ct, "application/vnd.openxmlformats-officedocument.spreadsheetml") ||
+		strings.EqualFold(filepath.Ext(doc.FileName), ".xlsx")
+}
+
+// spreadsheetExtractor lee planillas CSV/XLSX: la primera fila con columnas reconocibles es el encabezado
+type spreadsheetExtractor struct {
+	source DocumentSource
+}
+
+func NewSpreadsheetExtractor(source DocumentSource) Extractor {
+	return &spreadsheetExtractor{source: source}
+}
+
+func (e *spreadsheetExtractor) Extract(ctx context.Context, doc Document, opts Options) (Result, error) {
+	body, err := e.source.GetDocument(doc.Key)
+	if err != nil {
+		return Result{}, err
+	}
+	defer body.Close()
+
+	var rows [][]string
+	if isXLSX(doc) {
+		rows, err = readXLSX(body)
+	} else {
+		rows, err = readCSV(body)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("leyendo planilla %s: %w", doc.FileName, err)
+	}
+
+	mapping := ColumnMapping{}
+	if opts.Mapping != nil {
+		mapping = *opts.Mapping
+	}
+
+	headerRow, columns, ok := findHeader(rows, mapping)
+	if !ok {
+		return Result{}, ErrColumnsNotFound
+	}
+
+	lines := make([]bedrock.ProductResponse, 0)
+	for _, row := range rows[headerRow+1:] {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+
+		count, ok := utils.ParseQuantity(cell(row, columns.quantity))
+		if !ok {
+			continue
+		}
+		name := cell(row, columns.name)
+		sku := cell(row, columns.sku)
+		if name == "" && sku == "" {
+			continue
+		}
+		if name == "" {
+			name = sku
+		}
+
+		product := bedrock.ProductResponse{Name: name, Count: count, SKUs: []string{}}
+		if sku != "" {
+			product.SKUs = append(product.SKUs, sku)
+		}
+		lines = append(lines, product)
+	}
+
+	return Result{Lines: exactLines(lines)}, nil
+}
+
+type columnIndexes struct {
+	name     int
+	quantity int
+	sku      int
+}
+
+// findHeader busca entre las primeras filas la que tenga la columna de cantidad y al menos nombre o SKU
+func findHeader(rows [][]string, mapping ColumnMapping) (int, columnIndexes, bool) {
+	const maxHeaderRows = 10
+
+	for i, row := range rows {
+		if i >= maxHeaderRows {
+			break
+		}
+		columns := columnIndexes{
+			name:     findColumn(row, mapping.Name, nameHeaders),
+			quantity: findColumn(row, mapping.Quantity, quantityHeaders),
+			sku:      findColumn(row, mapping.Sku, skuHeaders),
+		}
+		if columns.quantity >= 0 && (columns.name >= 0 || columns.sku >= 0) {
+			return i, columns, true
+		}
+	}
+	return 0, columnIndexes{}, false
+}
+
+// findColumn devuelve el índice de la columna pedida en el mapeo o, si no hay, de la primera con un encabezado conocido
+func findColumn(row []string, mapped string, known []string) int {
+	if mapped != "" {
+		for i, value := range row {
+			if normalizeHeader(value) == normalizeHeader(mapped) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, header := range known {
+		for i, value := range row {
+			if normalizeHeader(value) == header {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", ".", "", ":", "").Replace(s)
+	return strings.TrimSpace(s)
+}
+
+func cell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// readCSV acepta coma o punto y coma como separador (Excel en español exporta con ;)
+func readCSV(body io.Reader) ([][]string, error) {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	raw = bytes.TrimPrefix(raw, []byte("\xef\xbb\xbf"))
+
+	firstLine := raw
+	if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+		firstLine = raw[:i]
+	}
+
+	reader := csv.NewReader(bytes.NewReader(raw))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	return reader.ReadAll()
+}
+
+// readXLSX lee la primera hoja del libro
+func readXLSX(body io.Reader) ([][]string, error) {
+	book, err := excelize.OpenReader(body)
+	if err != nil {
+		return nil, err
+	}
+	defer book.Close()
+
+	sheets := book.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("el libro no tiene hojas")
+	}
+	return book.GetRows(sheets[0])
+}
+
+// byType envía las planillas al lector de planillas y el resto de los documentos al backend configurado
+type byType struct {
+	spreadsheet Extractor
+	documents   Extractor
+}
+
+func (e *byType) Extract(ctx context.Context, doc Document, opts Options) (Result, error) {
+	if IsSpreadsheet(doc) {
+		return e.spreadsheet.Extract(ctx, doc, opts)
+	}
+	return e.documents.Extract(ctx, doc, opts)
+}
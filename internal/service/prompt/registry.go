@@ -0,0 +1,220 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	UseCaseProductExtraction = "product_extraction"
+	UseCaseChatbot           = "chatbot"
+	// UseCaseChatbotTools es el prompt de sistema de las sesiones de chat, que consultan los datos con herramientas
+	UseCaseChatbotTools = "chatbot_tools"
+	// UseCaseChatbotCatalog responde sobre todo el catálogo del cliente a partir de los agregados del esquema estrella
+	UseCaseChatbotCatalog = "chatbot_catalog"
+
+	// BuiltinVersion es la versión de los prompts que vienen en el código, usada si no hay ninguna activa en la base
+	BuiltinVersion = "v1"
+)
+
+var (
+	ErrPromptNotFound   = errors.New("versión de prompt no encontrada")
+	ErrInvalidPrompt    = errors.New("prompt inválido")
+	ErrDuplicateVersion = errors.New("la versión de prompt ya existe")
+)
+
+var builtin = map[string]map[string]string{
+	UseCaseProductExtraction: {BuiltinVersion: bedrock.ProductoPrompt},
+	UseCaseChatbot:           {BuiltinVersion: bedrock.ChatBot},
+	UseCaseChatbotTools:      {BuiltinVersion: bedrock.ChatBotTools},
+	UseCaseChatbotCatalog:    {BuiltinVersion: bedrock.ChatBotCatalog},
+}
+
+// Prompt es la plantilla resuelta para una invocación; Template lleva un %s donde va la entrada
+type Prompt struct {
+	UseCase  string
+	Version  string
+	Template string
+}
+
+type Registry interface {
+	// Resolve devuelve la versión pedida o, si version es vacío, la activa del cliente, la activa global
+	// o la del código, en ese orden
+	Resolve(ctx context.Context, useCase string, clientAccountId uuid.UUID, version string) (Prompt, error)
+	List(ctx context.Context, useCase string) ([]dto.PromptTemplateDto, error)
+	Create(ctx context.Context, actor string, prompt dto.CreatePromptTemplateDto) (dto.PromptTemplateDto, error)
+	Activate(ctx context.Context, id uuid.UUID) (dto.PromptTemplateDto, error)
+}
+
+type registry struct {
+	db *gorm.DB
+}
+
+func NewRegistry(db *gorm.DB) Registry {
+	return &registry{db: db}
+}
+
+func (r *registry) Resolve(ctx context.Context, useCase string, clientAccountId uuid.UUID, version string) (Prompt, error) {
+	query := r.db.WithContext(ctx).
+		Where("use_case = ?", useCase).
+		Where("client_account_id = ? OR client_account_id IS NULL", clientAccountId).
+		// la del cliente antes que la global
+		Order("client_account_id IS NULL")
+	if version != "" {
+		query = query.Where("version = ?", version)
+	} else {
+		query = query.Where("active")
+	}
+
+	var template models.PromptTemplate
+	err := query.First(&template).Error
+	if err == nil {
+		return Prompt{UseCase: useCase, Version: template.Version, Template: template.Template}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return Prompt{}, err
+	}
+
+	if version == "" {
+		version = BuiltinVersion
+	}
+	if text, ok := builtin[useCase][version]; ok {
+		return Prompt{UseCase: useCase, Version: version, Template: text}, nil
+	}
+	return Prompt{}, fmt.Errorf("%w: %s %s", ErrPromptNotFound, useCase, version)
+}
+
+func (r *registry) List(ctx context.Context, useCase string) ([]dto.PromptTemplateDto, error) {
+	query := r.db.WithContext(ctx).Order("use_case, client_account_id NULLS FIRST, created_at")
+	if useCase != "" {
+		query = query.Where("use_case = ?", useCase)
+	}
+
+	var templates []models.PromptTemplate
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.PromptTemplateDto, 0, len(templates))
+	for _, t := range templates {
+		items = append(items, toDto(t))
+	}
+	return items, nil
+}
+
+func (r *registry) Create(ctx context.Context, actor string, promptDto dto.CreatePromptTemplateDto) (dto.PromptTemplateDto, error) {
+	if _, ok := builtin[promptDto.UseCase]; !ok {
+		return dto.PromptTemplateDto{}, fmt.Errorf("%w: caso de uso desconocido %q", ErrInvalidPrompt, promptDto.UseCase)
+	}
+	if strings.TrimSpace(promptDto.Version) == "" {
+		return dto.PromptTemplateDto{}, fmt.Errorf("%w: la versión es obligatoria", ErrInvalidPrompt)
+	}
+	// la plantilla pasa por fmt.Sprintf: un % fuera del %s se leería como otro verbo y rompería la entrada
+	if strings.Count(promptDto.Template, "%s") != 1 || strings.Count(promptDto.Template, "%") != 1 {
+		return dto.PromptTemplateDto{}, fmt.Errorf("%w: la plantilla debe tener exactamente un %%s donde va la entrada y ningún otro %%", ErrInvalidPrompt)
+	}
+
+	template := models.PromptTemplate{
+		ID:              uuid.New(),
+		UseCase:         promptDto.UseCase,
+		Version:         strings.TrimSpace(promptDto.Version),
+		ClientAccountID: promptDto.ClientAccountId,
+		Template:        promptDto.Template,
+		CreatedBy:       actor,
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var exists int64
+		err := tx.Model(&models.PromptTemplate{}).
+			Where("use_case = ? AND version = ?", template.UseCase, template.Version).
+			Where(clientScope(template.ClientAccountID)).
+			Count(&exists).Error
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return fmt.Errorf("%w: %s %s", ErrDuplicateVersion, template.UseCase, template.Version)
+		}
+
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+		if promptDto.Activate {
+			return activate(tx, &template)
+		}
+		return nil
+	})
+	if err != nil {
+		return dto.PromptTemplateDto{}, err
+	}
+	return toDto(template), nil
+}
+
+// Activate deja la versión como la activa de su caso de uso y alcance (cliente o global)
+func (r *registry) Activate(ctx context.Context, id uuid.UUID) (dto.PromptTemplateDto, error) {
+	var template models.PromptTemplate
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&template, "id = ?", id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPromptNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return activate(tx, &template)
+	})
+	if err != nil {
+		return dto.PromptTemplateDto{}, err
+	}
+	return toDto(template), nil
+}
+
+func activate(tx *gorm.DB, template *models.PromptTemplate) error {
+	err := tx.Model(&models.PromptTemplate{}).
+		Where("use_case = ? AND active AND id <> ?", template.UseCase, template.ID).
+		Where(clientScope(template.ClientAccountID)).
+		Update("active", false).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template.Active = true
+	template.ActivatedAt = &now
+	return tx.Model(&models.PromptTemplate{}).Where("id = ?", template.ID).Updates(map[string]any{
+		"active":       true,
+		"activated_at": now,
+	}).Error
+}
+
+func clientScope(clientAccountId *uuid.UUID) clause.Expr {
+	if clientAccountId == nil {
+		return gorm.Expr("client_account_id IS NULL")
+	}
+	return gorm.Expr("client_account_id = ?", *clientAccountId)
+}
+
+func toDto(t models.PromptTemplate) dto.PromptTemplateDto {
+	return dto.PromptTemplateDto{
+		ID:              t.ID,
+		UseCase:         t.UseCase,
+		Version:         t.Version,
+		ClientAccountId: t.ClientAccountID,
+		Template:        t.Template,
+		Active:          t.Active,
+		CreatedBy:       t.CreatedBy,
+		CreatedAt:       t.CreatedAt,
+		ActivatedAt:     t.ActivatedAt,
+	}
+}
@@ -0,0 +1,301 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reassignMovement pasa una línea al producto que eligió el revisor: mueve el stock entre los dos productos,
+// aprende los códigos leídos como alias del producto elegido y elimina el producto que la solicitud había
+// creado para la línea si quedó sin movimientos. Si el producto no cambia es una corrección de cantidad.
+func reassignMovement(tx *gorm.DB, request models.Request, actor string, m dto.MovementsPatch) (eventservice.ProductAdjustEvent, error) {
+	movement, err := findRequestMovement(tx, request.ID, m.Id)
+	if err != nil {
+		return eventservice.ProductAdjustEvent{}, err
+	}
+	if movement.ProductID == m.ProductId {
+		return updateMovement(tx, request.ID, m)
+	}
+
+	var target models.Product
+	err = tx.First(&target, "id = ? AND client_account_id = ?", m.ProductId, request.ClientAccountID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("%w: %s", ErrUnknownProduct, m.ProductId)
+	}
+	if err != nil {
+		return eventservice.ProductAdjustEvent{}, err
+	}
+
+	sign := dto.GetTypeMovementForDeltaUpdate(movement.MovementTypeID)
+	removed := -sign * movement.Count
+	added := sign * m.Count
+
+	if err := tx.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", removed, movement.ProductID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("actualizando stock del producto %s: %w", movement.ProductID, err)
+	}
+	if err := tx.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", added, target.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("actualizando stock del producto %s: %w", target.ID, err)
+	}
+	if err := tx.Exec("UPDATE movement SET product_id = ?, count = ? WHERE id = ?", target.ID, m.Count, movement.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("reasignando movimiento: %w", err)
+	}
+	if err := tx.Exec("UPDATE request_per_product SET product_id = ? WHERE movement_id = ?", target.ID, movement.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("reasignando relación del movimiento: %w", err)
+	}
+	if err := tx.Exec("UPDATE request_line SET product_id = ?, count = ? WHERE movement_id = ?", target.ID, m.Count, movement.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("reasignando línea del movimiento: %w", err)
+	}
+
+	if err := learnAliases(tx, request, movement.ID, target.ID, actor); err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("guardando alias del producto %s: %w", target.ID, err)
+	}
+	if err := removeCreatedProduct(tx, request.ID, movement.ProductID); err != nil {
+		return eventservice.ProductAdjustEvent{}, err
+	}
+
+	return eventservice.ProductAdjustEvent{
+		ProductoID:        movement.ProductID.String(),
+		MovimientoID:      movement.ID.String(),
+		DeltaStock:        removed,
+		Cantidad:          m.Count,
+		ProductoDestinoID: target.ID.String(),
+		DeltaStockDestino: added,
+		NombreDestino:     target.Name,
+		ClienteID:         request.ClientAccountID.String(),
+	}, nil
+}
+
+// removeCreatedProduct elimina un producto que creó algún intento de la solicitud y que ya no tiene movimientos
+func removeCreatedProduct(tx *gorm.DB, requestId uuid.UUID, productId uuid.UUID) error {
+	var created int64
+	err := tx.Model(&models.RequestAttempt{}).
+		Where("request_id = ? AND created_products @> ?::jsonb", requestId, fmt.Sprintf("[%q]", productId)).
+		Count(&created).Error
+	if err != nil || created == 0 {
+		return err
+	}
+	return deleteOrphanProduct(tx, productId)
+}
+
+// deleteOrphanProduct elimina el producto y sus SKUs si no le quedan movimientos ni stock. Los movimientos los escribe
+// después el consumidor de MovementsEvent, así que un producto sin movimientos puede tener stock de otra solicitud:
+// el stock se revisa con el producto bloqueado antes de tocar sus SKUs.
+func deleteOrphanProduct(tx *gorm.DB, productId uuid.UUID) error {
+	var remaining int64
+	if err := tx.Model(&models.Movement{}).Where("product_id = ?", productId).Count(&remaining).Error; err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return nil
+	}
+
+	var stock []int
+	err := tx.Model(&models.Product{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", productId).
+		Pluck("stock", &stock).Error
+	if err != nil || len(stock) == 0 || stock[0] != 0 {
+		return err
+	}
+
+	if err := tx.Exec("DELETE FROM sku WHERE product_id = ?", productId).Error; err != nil {
+		return err
+	}
+	return tx.Exec("DELETE FROM product WHERE id = ?", productId).Error
+}
+
+// learnAliases guarda los SKUs y la descripción leídos en la línea como alias del producto elegido,
+// acotados al proveedor de la factura cuando se conoce
+func learnAliases(tx *gorm.DB, request models.Request, movementId uuid.UUID, productId uuid.UUID, actor string) error {
+	var line models.RequestLine
+	err := tx.First(&line, "movement_id = ?", movementId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// movimientos sin línea extraída (solicitudes manuales): no hay nada que aprender
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var skus []string
+	if line.Skus != "" {
+		if err := json.Unmarshal([]byte(line.Skus), &skus); err != nil {
+			return err
+		}
+	}
+
+	supplier, err := requestSupplier(tx, request.ID)
+	if err != nil {
+		return err
+	}
+	skuId, err := productSku(tx, productId)
+	if err != nil {
+		return err
+	}
+
+	for _, alias := range append(skus, line.Name) {
+		err := upsertAlias(tx, models.SkuAlias{
+			ClientAccountID: request.ClientAccountID,
+			SupplierTaxID:   supplier,
+			Alias:           alias,
+			ProductID:       productId,
+			SkuID:           skuId,
+			CreatedBy:       actor,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestSupplier devuelve el RUT del proveedor leído en los documentos de la solicitud, nil si no se conoce
+func requestSupplier(tx *gorm.DB, requestId uuid.UUID) (*string, error) {
+	var taxIds []string
+	err := tx.Model(&models.Documents{}).
+		Where("request_id = ? AND supplier_tax_id IS NOT NULL AND supplier_tax_id <> ''", requestId).
+		Order("created_at").
+		Limit(1).
+		Pluck("supplier_tax_id", &taxIds).Error
+	if err != nil || len(taxIds) == 0 {
+		return nil, err
+	}
+	return &taxIds[0], nil
+}
+
+// productSku devuelve el SKU activo más antiguo del producto, al que queda asociado el alias
+func productSku(tx *gorm.DB, productId uuid.UUID) (*uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := tx.Model(&models.Sku{}).
+		Where("product_id = ? AND status", productId).
+		Order("created_at").
+		Limit(1).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return &ids[0], nil
+}
+
+// upsertAlias crea el alias o, si ya existía para el cliente y proveedor, lo reasigna al producto nuevo
+func upsertAlias(tx *gorm.DB, alias models.SkuAlias) error {
+	alias.Alias = strings.TrimSpace(alias.Alias)
+	alias.AliasNormalized = normalizeSKU(alias.Alias)
+	if alias.AliasNormalized == "" {
+		return nil
+	}
+
+	return tx.Exec(`
+		INSERT INTO sku_alias (id, client_account_id, supplier_tax_id, alias, alias_normalized, product_id, sku_id, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+		ON CONFLICT (client_account_id, COALESCE(supplier_tax_id, ''), alias_normalized)
+		DO UPDATE SET alias = EXCLUDED.alias, product_id = EXCLUDED.product_id, sku_id = EXCLUDED.sku_id,
+			created_by = EXCLUDED.created_by, updated_at = NOW()
+	`, uuid.New(), alias.ClientAccountID, alias.SupplierTaxID, alias.Alias, alias.AliasNormalized,
+		alias.ProductID, alias.SkuID, alias.CreatedBy).Error
+}
+
+// SkuAliases lista los alias de la cuenta; productId distinto de uuid.Nil filtra por producto
+func (r requestService) SkuAliases(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID) ([]dto.SkuAliasDto, error) {
+	query := r.db.WithContext(ctx).Where("client_account_id = ?", clientAccountId)
+	if productId != uuid.Nil {
+		query = query.Where("product_id = ?", productId)
+	}
+
+	var aliases []models.SkuAlias
+	if err := query.Order("alias_normalized, supplier_tax_id").Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.SkuAliasDto, 0, len(aliases))
+	for _, alias := range aliases {
+		items = append(items, toSkuAliasDto(alias))
+	}
+	return items, nil
+}
+
+// SaveSkuAlias crea un alias a mano o reasigna uno existente al producto indicado
+func (r requestService) SaveSkuAlias(ctx context.Context, clientAccountId uuid.UUID, actor string, aliasDto dto.CreateSkuAliasDto) (dto.SkuAliasDto, error) {
+	if normalizeSKU(aliasDto.Alias) == "" {
+		return dto.SkuAliasDto{}, fmt.Errorf("%w: el alias debe tener letras o números", ErrInvalidSkuAlias)
+	}
+	if aliasDto.SupplierTaxId != nil && strings.TrimSpace(*aliasDto.SupplierTaxId) == "" {
+		aliasDto.SupplierTaxId = nil
+	}
+
+	var alias models.SkuAlias
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		err := tx.First(&product, "id = ? AND client_account_id = ?", aliasDto.ProductId, clientAccountId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s", ErrUnknownProduct, aliasDto.ProductId)
+		}
+		if err != nil {
+			return err
+		}
+
+		skuId, err := productSku(tx, product.ID)
+		if err != nil {
+			return err
+		}
+		err = upsertAlias(tx, models.SkuAlias{
+			ClientAccountID: clientAccountId,
+			SupplierTaxID:   aliasDto.SupplierTaxId,
+			Alias:           aliasDto.Alias,
+			ProductID:       product.ID,
+			SkuID:           skuId,
+			CreatedBy:       actor,
+		})
+		if err != nil {
+			return err
+		}
+
+		query := tx.Where("client_account_id = ? AND alias_normalized = ?", clientAccountId, normalizeSKU(aliasDto.Alias))
+		if aliasDto.SupplierTaxId != nil {
+			query = query.Where("supplier_tax_id = ?", *aliasDto.SupplierTaxId)
+		} else {
+			query = query.Where("supplier_tax_id IS NULL")
+		}
+		return query.First(&alias).Error
+	})
+	if err != nil {
+		return dto.SkuAliasDto{}, err
+	}
+	return toSkuAliasDto(alias), nil
+}
+
+func (r requestService) DeleteSkuAlias(ctx context.Context, clientAccountId uuid.UUID, aliasId uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND client_account_id = ?", aliasId, clientAccountId).
+		Delete(&models.SkuAlias{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSkuAliasNotFound
+	}
+	return nil
+}
+
+func toSkuAliasDto(alias models.SkuAlias) dto.SkuAliasDto {
+	return dto.SkuAliasDto{
+		ID:            alias.ID,
+		Alias:         alias.Alias,
+		SupplierTaxId: alias.SupplierTaxID,
+		ProductId:     alias.ProductID,
+		SkuId:         alias.SkuID,
+		CreatedBy:     alias.CreatedBy,
+		CreatedAt:     alias.CreatedAt,
+		UpdatedAt:     alias.UpdatedAt,
+	}
+}
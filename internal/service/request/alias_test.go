@@ -0,0 +1,141 @@
+package request
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// scriptedRows es la respuesta a las consultas que contienen match
+type scriptedRows struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+}
+
+// scriptedDriver responde las consultas según el guion y registra todo lo que se ejecuta
+type scriptedDriver struct {
+	mu         sync.Mutex
+	script     []scriptedRows
+	statements []string
+}
+
+func (d *scriptedDriver) Open(string) (driver.Conn, error) { return &scriptedConn{driver: d}, nil }
+
+func (d *scriptedDriver) executed(fragment string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, statement := range d.statements {
+		if strings.Contains(statement, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+type scriptedConn struct{ driver *scriptedDriver }
+
+func (c *scriptedConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *scriptedConn) Close() error                        { return nil }
+func (c *scriptedConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *scriptedConn) Commit() error                       { return nil }
+func (c *scriptedConn) Rollback() error                     { return nil }
+func (c *scriptedConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *scriptedConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.statements = append(c.driver.statements, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *scriptedConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.statements = append(c.driver.statements, query)
+	for _, s := range c.driver.script {
+		if strings.Contains(query, s.match) {
+			return &rowsIterator{columns: s.columns, rows: s.rows}, nil
+		}
+	}
+	return &rowsIterator{}, nil
+}
+
+type rowsIterator struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rowsIterator) Columns() []string { return r.columns }
+func (r *rowsIterator) Close() error      { return nil }
+func (r *rowsIterator) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func scriptedDB(t *testing.T, script ...scriptedRows) (*gorm.DB, *scriptedDriver) {
+	t.Helper()
+	d := &scriptedDriver{script: script}
+	name := "scripted-" + uuid.NewString()
+	sql.Register(name, d)
+	conn, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, d
+}
+
+// Al reasignar una línea, el producto que había creado la solicitud puede no tener movimientos todavía
+// (los escribe el consumidor de MovementsEvent) y sí stock de otra solicitud: no se le pueden borrar los SKUs.
+func TestRemoveCreatedProductKeepsProductWithStock(t *testing.T) {
+	tests := []struct {
+		name    string
+		stock   int64
+		deleted bool
+	}{
+		{"con stock de otra solicitud", 7, false},
+		{"sin stock", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, d := scriptedDB(t,
+				scriptedRows{match: `FROM "request_attempt"`, columns: []string{"count"}, rows: [][]driver.Value{{int64(1)}}},
+				scriptedRows{match: `FROM "movement"`, columns: []string{"count"}, rows: [][]driver.Value{{int64(0)}}},
+				scriptedRows{match: `FROM "product"`, columns: []string{"stock"}, rows: [][]driver.Value{{tt.stock}}},
+			)
+
+			if err := removeCreatedProduct(db, uuid.New(), uuid.New()); err != nil {
+				t.Fatalf("removeCreatedProduct: %v", err)
+			}
+			if !d.executed("FOR UPDATE") {
+				t.Error("el stock debe revisarse con el producto bloqueado")
+			}
+			if got := d.executed("DELETE FROM sku"); got != tt.deleted {
+				t.Errorf("DELETE FROM sku ejecutado = %v; se esperaba %v", got, tt.deleted)
+			}
+			if got := d.executed("DELETE FROM product"); got != tt.deleted {
+				t.Errorf("DELETE FROM product ejecutado = %v; se esperaba %v", got, tt.deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+	"github.com/stock-ahora/api-stock/internal/service/extractor"
+	"github.com/stock-ahora/api-stock/internal/service/prompt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func startAttempt(db *gorm.DB, requestId uuid.UUID, model string, promptVersion string) (models.RequestAttempt, error) {
+	var last int
+	err := db.Model(&models.RequestAttempt{}).
+		Select("COALESCE(MAX(attempt), 0)").
+		Where("request_id = ?", requestId).
+		Scan(&last).Error
+	if err != nil {
+		return models.RequestAttempt{}, err
+	}
+
+	attempt := models.RequestAttempt{
+		ID:            uuid.New(),
+		RequestID:     requestId,
+		Attempt:       last + 1,
+		Model:         model,
+		PromptVersion: promptVersion,
+		Status:        models.AttemptRunning,
+		StartedAt:     time.Now(),
+	}
+	return attempt, db.Create(&attempt).Error
+}
+
+func finishAttempt(db *gorm.DB, attempt models.RequestAttempt, status models.AttemptStatus, lines int, createdProducts []uuid.UUID, cause error) error {
+	updates := map[string]any{
+		"status":      status,
+		"lines":       lines,
+		"finished_at": time.Now(),
+	}
+	if cause != nil {
+		updates["error"] = cause.Error()
+	}
+	if len(createdProducts) > 0 {
+		raw, err := json.Marshal(createdProducts)
+		if err != nil {
+			return err
+		}
+		updates["created_products"] = string(raw)
+	}
+	return db.Model(&models.RequestAttempt{}).Where("id = ?", attempt.ID).Updates(updates).Error
+}
+
+// rejectAttempt deja la solicitud rechazada y el intento con su causa, en una sola transacción
+func rejectAttempt(db *gorm.DB, evt eventservice.RequestProcessEvent, attempt models.RequestAttempt, cause error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := markProcessed(tx, evt, evt.RequestID, models.RequestStatusRejected); err != nil {
+			return err
+		}
+		return finishAttempt(tx, attempt, models.AttemptRejected, 0, nil, cause)
+	})
+}
+
+// attemptStaleAfter es cuánto puede seguir un intento en running antes de darlo por perdido
+// (la instancia que lo ejecutaba cayó a mitad); supera con holgura la espera de Textract por entrega
+const attemptStaleAfter = 10 * time.Minute
+
+// lastAttemptFailed indica si el último intento de la solicitud falló (o quedó abandonado en running)
+// y no hay otro ejecutándose
+func lastAttemptFailed(tx *gorm.DB, requestId uuid.UUID) (bool, error) {
+	var attempts []models.RequestAttempt
+	err := tx.Where("request_id = ?", requestId).Order("attempt DESC").Limit(1).Find(&attempts).Error
+	if err != nil || len(attempts) == 0 {
+		return false, err
+	}
+
+	last := attempts[0]
+	switch last.Status {
+	case models.AttemptFailed:
+		return true, nil
+	case models.AttemptRunning:
+		return last.StartedAt.Before(time.Now().Add(-attemptStaleAfter)), nil
+	default:
+		return false, nil
+	}
+}
+
+func (r requestService) Attempts(ctx context.Context, clientAccountId uuid.UUID, requestId uuid.UUID) ([]dto.RequestAttemptDto, error) {
+	var request models.Request
+
+	err := r.db.WithContext(ctx).First(&request, "id = ? AND client_account_id = ?", requestId, clientAccountId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var attempts []models.RequestAttempt
+	err = r.db.WithContext(ctx).
+		Where("request_id = ?", requestId).
+		Order("attempt").
+		Find(&attempts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.RequestAttemptDto, 0, len(attempts))
+	for _, a := range attempts {
+		items = append(items, dto.RequestAttemptDto{
+			Attempt:       a.Attempt,
+			Model:         a.Model,
+			PromptVersion: a.PromptVersion,
+			Status:        string(a.Status),
+			Lines:         a.Lines,
+			Error:         a.Error,
+			StartedAt:     a.StartedAt,
+			FinishedAt:    a.FinishedAt,
+		})
+	}
+	return items, nil
+}
+
+// Reprocess vuelve a encolar el procesamiento de una solicitud rechazada o cuyo último intento falló,
+// limpiando lo que dejó el intento anterior.
+func (r requestService) Reprocess(ctx context.Context, clientAccountId uuid.UUID, actor string, requestId uuid.UUID, options dto.ReprocessRequestDto) error {
+	if options.Model != "" && !bedrock.IsSupportedModel(options.Model) {
+		return fmt.Errorf("%w: modelo %s", ErrInvalidReprocessOptions, options.Model)
+	}
+	if options.PromptVersion != "" {
+		_, err := r.prompts.Resolve(ctx, prompt.UseCaseProductExtraction, clientAccountId, options.PromptVersion)
+		if errors.Is(err, prompt.ErrPromptNotFound) {
+			return fmt.Errorf("%w: %v", ErrInvalidReprocessOptions, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var request models.Request
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&request, "id = ? AND client_account_id = ?", requestId, clientAccountId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRequestNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// una solicitud que sigue en created solo se reprocesa si su último intento falló y ya no corre ninguno
+		retry := request.Status == models.RequestCreated
+		if retry {
+			failed, err := lastAttemptFailed(tx, request.ID)
+			if err != nil {
+				return err
+			}
+			if !failed {
+				return ErrNotReprocessable
+			}
+		} else if !request.Status.CanTransitionTo(models.RequestCreated) {
+			return &TransitionError{RequestID: request.ID, From: request.Status, To: models.RequestCreated}
+		}
+
+		var documents []models.Documents
+		if err := tx.Where("request_id = ?", request.ID).Find(&documents).Error; err != nil {
+			return err
+		}
+		pending := make([]extractor.Document, 0, len(documents))
+		for _, document := range documents {
+			pending = append(pending, extractor.Document{FileName: document.FileName, ContentType: document.ContentType})
+		}
+		if err := r.checkOCRQuota(ctx, clientAccountId, pending); err != nil {
+			return err
+		}
+
+		if err := r.clearPreviousAttempt(tx, request); err != nil {
+			return err
+		}
+
+		if retry {
+			err = recordStatus(tx, request.ID, request.Status, models.RequestCreated, actor, "reprocesamiento tras intento fallido")
+		} else {
+			err = transition(tx, request.ID, request.Status, models.RequestCreated, actor, "reprocesamiento")
+		}
+		if err != nil {
+			return err
+		}
+
+		event := createdRequestProcess(request.ID, request.ClientAccountID, dto.GetTypeMovementForDeltaUpdate(request.MovementTypeId))
+		event.Model = options.Model
+		event.PromptVersion = options.PromptVersion
+		return r.eventSvc.EnqueueRequest(tx, event)
+	})
+}
+
+// clearPreviousAttempt revierte el stock que dejó un intento anterior, elimina sus movimientos y los productos
+// que ese intento creó y que ya no tienen movimientos ni stock. Como en Cancel, el stock se revierte desde
+// request_line y no desde los movimientos, que el consumidor de MovementsEvent puede no haber escrito todavía.
+func (r requestService) clearPreviousAttempt(tx *gorm.DB, request models.Request) error {
+	applied, err := appliedStock(tx, request)
+	if err != nil {
+		return err
+	}
+
+	sign := dto.GetTypeMovementForDeltaUpdate(request.MovementTypeId)
+	for _, line := range applied {
+		if line.count == 0 {
+			continue
+		}
+
+		delta := -sign * line.count
+		if err := tx.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", delta, line.productId).Error; err != nil {
+			return fmt.Errorf("revirtiendo stock del producto %s: %w", line.productId, err)
+		}
+		err := r.eventSvc.EnqueueProductAdjust(tx, eventservice.ProductAdjustEvent{
+			ProductoID:   line.productId.String(),
+			MovimientoID: line.movementId.String(),
+			DeltaStock:   delta,
+			Cantidad:     0,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// las líneas se conservan para la revisión, pero ya no aportan stock
+	if err := tx.Exec("UPDATE request_line SET count = 0 WHERE request_id = ?", request.ID).Error; err != nil {
+		return fmt.Errorf("actualizando líneas de la solicitud: %w", err)
+	}
+	err = tx.Exec("DELETE FROM request_per_product WHERE movement_id IN (SELECT id FROM movement WHERE request_id = ?)", request.ID).Error
+	if err != nil {
+		return fmt.Errorf("eliminando relación de los movimientos: %w", err)
+	}
+	if err := tx.Exec("DELETE FROM movement WHERE request_id = ?", request.ID).Error; err != nil {
+		return fmt.Errorf("eliminando movimientos: %w", err)
+	}
+
+	var attempts []models.RequestAttempt
+	err = tx.Where("request_id = ? AND created_products IS NOT NULL", request.ID).Find(&attempts).Error
+	if err != nil {
+		return err
+	}
+
+	for _, attempt := range attempts {
+		var productIds []uuid.UUID
+		if err := json.Unmarshal([]byte(attempt.CreatedProducts), &productIds); err != nil {
+			return err
+		}
+
+		for _, productId := range productIds {
+			if err := deleteOrphanProduct(tx, productId); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Cancel anula la solicitud y revierte el stock que aplicaron sus movimientos con movimientos compensatorios.
+func (r requestService) Cancel(ctx context.Context, clientAccountId uuid.UUID, actor string, requestId uuid.UUID, reason string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var request models.Request
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&request, "id = ? AND client_account_id = ?", requestId, clientAccountId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRequestNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !request.Status.CanTransitionTo(models.RequestStatusCancelled) {
+			return &TransitionError{RequestID: request.ID, From: request.Status, To: models.RequestStatusCancelled}
+		}
+
+		if err := r.compensateMovements(tx, request); err != nil {
+			return err
+		}
+
+		shortID := strings.Split(request.ID.String(), "-")[0]
+		err = tx.Exec(
+			"INSERT INTO public.notification (message, type, is_read, date) VALUES (?, ?, ?, ?)",
+			"Solicitud cancelada "+shortID+": "+reason,
+			"request",
+			false,
+			time.Now(),
+		).Error
+		if err != nil {
+			return err
+		}
+
+		return transition(tx, request.ID, request.Status, models.RequestStatusCancelled, actor, reason)
+	})
+}
+
+// compensation es el stock que una línea de la solicitud dejó aplicado en un producto
+type compensation struct {
+	productId  uuid.UUID
+	movementId uuid.UUID
+	count      int
+}
+
+// compensateMovements deshace el stock que aplicó la solicitud y publica el movimiento inverso de cada producto,
+// que llega a dim_producto y fact_product_movement por el ETL
+func (r requestService) compensateMovements(tx *gorm.DB, request models.Request) error {
+	applied, err := appliedStock(tx, request)
+	if err != nil {
+		return err
+	}
+
+	// signo con que la solicitud afectó el stock
+	originalSign := dto.GetTypeMovementForDeltaUpdate(request.MovementTypeId)
+
+	compensations := make([]eventservice.ProductPerMovement, 0, len(applied))
+	for _, line := range applied {
+		if line.count == 0 {
+			continue
+		}
+
+		delta := -originalSign * line.count
+		if err := tx.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", delta, line.productId).Error; err != nil {
+			return fmt.Errorf("revirtiendo stock del producto %s: %w", line.productId, err)
+		}
+
+		var product models.Product
+		if err := tx.First(&product, "id = ?", line.productId).Error; err != nil {
+			return fmt.Errorf("obteniendo producto %s: %w", line.productId, err)
+		}
+
+		compensation := createMovement(product, line.count, -originalSign)
+		compensations = append(compensations, compensation)
+
+		err := r.eventSvc.EnqueueProductEtl(tx, eventservice.ProductEvent{
+			ProductoID:      product.ID.String(),
+			NombreProducto:  product.Name,
+			ClienteID:       request.ClientAccountID.String(),
+			MovimientoID:    compensation.MovementId.String(),
+			Cantidad:        compensation.Count,
+			Signo:           -originalSign,
+			Fecha:           compensation.CreatedAt,
+			SolicitudId:     request.ID.String(),
+			StatusSolicitud: string(models.RequestStatusCancelled),
+			TipoMovimiento:  fmt.Sprintf("%d", compensation.MovementTypeId),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(compensations) == 0 {
+		return nil
+	}
+
+	return r.eventSvc.EnqueueMovements(tx, eventservice.MovementsEvent{
+		Id:                 uuid.New(),
+		ProductPerMovement: compensations,
+		RequestId:          request.ID,
+	})
+}
+
+// appliedStock devuelve el stock vigente de cada línea de la solicitud. Se lee de request_line, que se escribe en la
+// misma transacción que el stock y que la confirmación mantiene al día con las cantidades editadas; los movimientos
+// los crea después el consumidor de MovementsEvent y pueden no existir todavía. Las solicitudes anteriores a
+// request_line no tienen líneas y se compensan desde sus movimientos.
+func appliedStock(tx *gorm.DB, request models.Request) ([]compensation, error) {
+	var lines []models.RequestLine
+	if err := tx.Where("request_id = ?", request.ID).Order("created_at").Find(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		var movements []models.Movement
+		if err := tx.Where("request_id = ?", request.ID).Find(&movements).Error; err != nil {
+			return nil, err
+		}
+		applied := make([]compensation, 0, len(movements))
+		for _, movement := range movements {
+			applied = append(applied, compensation{productId: movement.ProductID, movementId: movement.ID, count: movement.Count})
+		}
+		return applied, nil
+	}
+
+	productIds := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		productIds = append(productIds, line.ProductID)
+	}
+	var found []uuid.UUID
+	err := tx.Model(&models.Product{}).
+		Where("id IN ? AND client_account_id = ?", productIds, request.ClientAccountID).
+		Pluck("id", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[uuid.UUID]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+
+	applied := make([]compensation, 0, len(lines))
+	unresolved := make([]uuid.UUID, 0)
+	for _, line := range lines {
+		if line.Count != 0 && !exists[line.ProductID] {
+			unresolved = append(unresolved, line.MovementID)
+			continue
+		}
+		applied = append(applied, compensation{productId: line.ProductID, movementId: line.MovementID, count: line.Count})
+	}
+	if len(unresolved) > 0 {
+		return nil, fmt.Errorf("%w: líneas de los movimientos %v", ErrUncompensableLines, unresolved)
+	}
+	return applied, nil
+}
@@ -0,0 +1,84 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/extractor"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// findColumnMapping devuelve el mapeo guardado del cliente o nil para usar la detección de encabezados
+func findColumnMapping(db *gorm.DB, clientAccountId uuid.UUID) (*extractor.ColumnMapping, error) {
+	var mapping models.ColumnMapping
+
+	err := db.First(&mapping, "client_account_id = ?", clientAccountId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &extractor.ColumnMapping{
+		Name:     mapping.NameColumn,
+		Quantity: mapping.QuantityColumn,
+		Sku:      mapping.SkuColumn,
+	}, nil
+}
+
+func (r requestService) ColumnMapping(ctx context.Context, clientAccountId uuid.UUID) (dto.ColumnMappingDto, error) {
+	var mapping models.ColumnMapping
+
+	err := r.db.WithContext(ctx).First(&mapping, "client_account_id = ?", clientAccountId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.ColumnMappingDto{}, ErrColumnMappingNotFound
+	}
+	if err != nil {
+		return dto.ColumnMappingDto{}, err
+	}
+
+	return toColumnMappingDto(mapping), nil
+}
+
+// SaveColumnMapping guarda (o reemplaza) el mapeo de columnas que se usa con las planillas del cliente
+func (r requestService) SaveColumnMapping(ctx context.Context, clientAccountId uuid.UUID, mappingDto dto.ColumnMappingDto) (dto.ColumnMappingDto, error) {
+	mapping := models.ColumnMapping{
+		ID:              uuid.New(),
+		ClientAccountID: clientAccountId,
+		NameColumn:      strings.TrimSpace(mappingDto.NameColumn),
+		QuantityColumn:  strings.TrimSpace(mappingDto.QuantityColumn),
+		SkuColumn:       strings.TrimSpace(mappingDto.SkuColumn),
+	}
+	if mapping.QuantityColumn == "" {
+		return dto.ColumnMappingDto{}, fmt.Errorf("%w: la columna de cantidad es obligatoria", ErrInvalidColumnMapping)
+	}
+	if mapping.NameColumn == "" && mapping.SkuColumn == "" {
+		return dto.ColumnMappingDto{}, fmt.Errorf("%w: se requiere la columna de nombre o de SKU", ErrInvalidColumnMapping)
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name_column", "quantity_column", "sku_column", "updated_at"}),
+	}).Create(&mapping).Error
+	if err != nil {
+		return dto.ColumnMappingDto{}, err
+	}
+
+	return toColumnMappingDto(mapping), nil
+}
+
+func toColumnMappingDto(mapping models.ColumnMapping) dto.ColumnMappingDto {
+	return dto.ColumnMappingDto{
+		NameColumn:     mapping.NameColumn,
+		QuantityColumn: mapping.QuantityColumn,
+		SkuColumn:      mapping.SkuColumn,
+		UpdatedAt:      mapping.UpdatedAt,
+	}
+}
@@ -0,0 +1,129 @@
+package request
+
+import (
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"gorm.io/gorm"
+)
+
+// Códigos de motivo que explican por qué una línea tiene baja confianza
+const (
+	ReasonLowOCRConfidence  = "LOW_OCR_CONFIDENCE"
+	ReasonSkuGenerated      = "SKU_GENERATED"
+	ReasonSkuFuzzyMatch     = "SKU_FUZZY_MATCH"
+	ReasonSkuAmbiguous      = "SKU_AMBIGUOUS"
+	ReasonNewProduct        = "NEW_PRODUCT"
+	ReasonQuantityAmbiguous = "QUANTITY_AMBIGUOUS"
+	ReasonSourceRowNotFound = "SOURCE_ROW_NOT_FOUND"
+	ReasonDocumentsDisagree = "DOCUMENTS_DISAGREE"
+)
+
+const lowOCRConfidence = 0.9
+
+// skuMatch describe cómo se resolvió el producto de la línea: Score es el puntaje (0-1) del candidato enlazado.
+// Ambiguous indica que hubo candidatos pero ninguno alcanzó para enlazarlo sin revisión.
+type skuMatch struct {
+	Found      bool
+	Score      float64
+	Candidate  skuCandidate
+	Ambiguous  bool
+	Candidates []skuCandidate
+}
+
+type lineScore struct {
+	Confidence  float64
+	Reasons     []string
+	NeedsReview bool
+}
+
+// scoreLine combina las señales del documento y del match de SKU en una confianza 0-1; cada señal
+// desfavorable la multiplica por un factor y agrega su motivo. Bajo threshold la línea exige revisión.
+func scoreLine(line lineItem, match skuMatch, threshold float64) lineScore {
+	// producto elegido por el usuario (solicitud manual)
+	if line.ProductID != uuid.Nil {
+		return lineScore{Confidence: 1, Reasons: []string{}}
+	}
+
+	confidence := 1.0
+	reasons := make([]string, 0)
+	penalize := func(factor float64, reason string) {
+		confidence *= factor
+		reasons = append(reasons, reason)
+	}
+
+	if line.OCRConfidence != nil {
+		confidence *= *line.OCRConfidence
+		if *line.OCRConfidence < lowOCRConfidence {
+			reasons = append(reasons, ReasonLowOCRConfidence)
+		}
+	}
+	if !line.SkuSupplied {
+		penalize(0.85, ReasonSkuGenerated)
+	}
+	switch {
+	case match.Ambiguous:
+		penalize(0.6, ReasonSkuAmbiguous)
+	case !match.Found:
+		penalize(0.9, ReasonNewProduct)
+	case match.Score < 1:
+		penalize(math.Max(match.Score, 0.5), ReasonSkuFuzzyMatch)
+	}
+	if line.QuantityAmbiguous {
+		penalize(0.7, ReasonQuantityAmbiguous)
+	}
+	if !line.SourceRowFound {
+		penalize(0.7, ReasonSourceRowNotFound)
+	}
+	if line.DocumentsDisagree {
+		penalize(0.8, ReasonDocumentsDisagree)
+	}
+
+	confidence = math.Round(confidence*100) / 100
+	// una línea ambigua siempre la resuelve el revisor, aunque el resto de las señales sean buenas
+	return lineScore{Confidence: confidence, Reasons: reasons, NeedsReview: confidence < threshold || match.Ambiguous}
+}
+
+// skuSimilarity es 1 - distancia de edición normalizada entre los SKUs normalizados
+func skuSimilarity(a, b string) float64 {
+	a, b = normalizeSKU(a), normalizeSKU(b)
+	if a == b {
+		return 1
+	}
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// pendingReview devuelve los movimientos de la solicitud marcados para revisión obligatoria
+func pendingReview(tx *gorm.DB, requestId uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := tx.Model(&models.RequestLine{}).
+		Where("request_id = ? AND needs_review", requestId).
+		Pluck("movement_id", &ids).Error
+	return ids, err
+}
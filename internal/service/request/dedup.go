@@ -0,0 +1,62 @@
+package request
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"gorm.io/gorm"
+)
+
+// hashUpload calcula el SHA-256 del archivo y lo deja posicionado al inicio para subirlo
+func hashUpload(file dto.UploadFile) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file.File); err != nil {
+		return "", err
+	}
+	if _, err := file.File.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// findDuplicate busca un documento con el mismo contenido en otra solicitud vigente (no cancelada) del cliente
+func findDuplicate(db *gorm.DB, clientAccountId uuid.UUID, hashes map[string]string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(hashes))
+	for hash := range hashes {
+		list = append(list, hash)
+	}
+
+	var original models.Documents
+	err := db.Joins("JOIN request ON request.id = documents.request_id").
+		Where("request.client_account_id = ?", clientAccountId).
+		Where("request.status <> ?", models.RequestStatusCancelled).
+		Where("documents.content_hash IN ?", list).
+		Order("documents.created_at").
+		First(&original).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return &DuplicateDocumentError{
+		FileName:          hashes[original.ContentHash],
+		ContentHash:       original.ContentHash,
+		OriginalRequestID: original.RequestID,
+	}
+}
+
+// lockClientUploads serializa dentro de la transacción las cargas del cliente para que dos subidas
+// simultáneas del mismo archivo no pasen ambas la verificación
+func lockClientUploads(tx *gorm.DB, clientAccountId uuid.UUID) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "request-upload:"+clientAccountId.String()).Error
+}
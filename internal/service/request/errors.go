@@ -0,0 +1,79 @@
+package request
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/models"
+)
+
+var (
+	ErrRequestNotFound  = errors.New("solicitud no encontrada")
+	ErrMovementNotFound = errors.New("movimiento no encontrado en la solicitud")
+
+	// ErrRequestAlreadyProcessed indica una entrega duplicada: se confirma sin efectos
+	ErrRequestAlreadyProcessed = errors.New("la solicitud ya fue procesada")
+
+	ErrInvalidReprocessOptions = errors.New("opciones de reprocesamiento inválidas")
+	ErrNotReprocessable        = errors.New("la solicitud se está procesando o su último intento no falló")
+
+	// ErrUncompensableLines indica líneas cuyo producto ya no existe: no se sabe dónde revertir su stock
+	ErrUncompensableLines = errors.New("la solicitud tiene líneas que no se pueden compensar")
+
+	ErrInvalidManualRequest = errors.New("solicitud manual inválida")
+	ErrUnknownProduct       = errors.New("producto no encontrado para la cuenta cliente")
+
+	ErrColumnMappingNotFound = errors.New("la cuenta cliente no tiene un mapeo de columnas guardado")
+	ErrInvalidColumnMapping  = errors.New("mapeo de columnas inválido")
+
+	ErrSkuAliasNotFound = errors.New("alias de SKU no encontrado")
+	ErrInvalidSkuAlias  = errors.New("alias de SKU inválido")
+)
+
+// ConfirmError indica que la confirmación se revirtió completa porque falló uno de sus movimientos.
+type ConfirmError struct {
+	RequestID  uuid.UUID
+	MovementID uuid.UUID
+	Err        error
+}
+
+func (e *ConfirmError) Error() string {
+	return fmt.Sprintf("confirmación de la solicitud %s revertida en el movimiento %s: %v", e.RequestID, e.MovementID, e.Err)
+}
+
+func (e *ConfirmError) Unwrap() error {
+	return e.Err
+}
+
+// TransitionError indica un cambio de estado no permitido por la tabla de transiciones.
+type TransitionError struct {
+	RequestID uuid.UUID
+	From      models.RequestStatus
+	To        models.RequestStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("la solicitud %s no puede pasar de %q a %q", e.RequestID, e.From, e.To)
+}
+
+// ReviewRequiredError indica que la confirmación no incluyó movimientos marcados para revisión obligatoria.
+type ReviewRequiredError struct {
+	RequestID   uuid.UUID
+	MovementIDs []uuid.UUID
+}
+
+func (e *ReviewRequiredError) Error() string {
+	return fmt.Sprintf("la solicitud %s tiene %d movimiento(s) que requieren revisión: %v", e.RequestID, len(e.MovementIDs), e.MovementIDs)
+}
+
+// DuplicateDocumentError indica que el cliente ya cargó un archivo con el mismo contenido en otra solicitud.
+type DuplicateDocumentError struct {
+	FileName          string
+	ContentHash       string
+	OriginalRequestID uuid.UUID
+}
+
+func (e *DuplicateDocumentError) Error() string {
+	return fmt.Sprintf("el archivo %s ya fue cargado en la solicitud %s", e.FileName, e.OriginalRequestID)
+}
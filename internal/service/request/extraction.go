@@ -0,0 +1,42 @@
+package request
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"gorm.io/gorm"
+)
+
+// saveExtractions registra en el documento cada llamada al LLM del intento, válida o no
+func saveExtractions(db *gorm.DB, attemptId uuid.UUID, documentId uuid.UUID, attempts []bedrock.ExtractionAttempt) error {
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	rows := make([]models.DocumentExtraction, 0, len(attempts))
+	for _, a := range attempts {
+		row := models.DocumentExtraction{
+			ID:               uuid.New(),
+			DocumentID:       documentId,
+			RequestAttemptID: attemptId,
+			Attempt:          a.Attempt,
+			Model:            a.Model,
+			Output:           a.Output,
+			Valid:            len(a.Errors) == 0,
+			InputTokens:      a.Usage.InputTokens,
+			OutputTokens:     a.Usage.OutputTokens,
+		}
+		if len(a.Errors) > 0 {
+			raw, err := json.Marshal(a.Errors)
+			if err != nil {
+				return err
+			}
+			row.Errors = string(raw)
+		}
+		rows = append(rows, row)
+	}
+
+	return db.Create(&rows).Error
+}
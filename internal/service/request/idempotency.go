@@ -0,0 +1,49 @@
+package request
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func eventKey(evt eventservice.RequestProcessEvent) string {
+	if evt.EventID == "" {
+		return evt.RequestID.String()
+	}
+	return evt.EventID
+}
+
+func (r requestService) isEventProcessed(ctx context.Context, evt eventservice.RequestProcessEvent) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ProcessedEvent{}).
+		Where("event_id = ? AND request_id = ?", eventKey(evt), evt.RequestID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// markProcessed registra el evento y mueve la solicitud desde created en la misma transacción que sus efectos.
+// Si otra entrega ya lo hizo devuelve ErrRequestAlreadyProcessed para que el llamador haga rollback.
+func markProcessed(tx *gorm.DB, evt eventservice.RequestProcessEvent, requestId uuid.UUID, status models.RequestStatus) error {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ProcessedEvent{
+		EventID:   eventKey(evt),
+		RequestID: requestId,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRequestAlreadyProcessed
+	}
+
+	err := transition(tx, requestId, models.RequestCreated, status, SystemActor, "procesamiento del evento "+eventKey(evt))
+	var transitionErr *TransitionError
+	if errors.As(err, &transitionErr) {
+		return ErrRequestAlreadyProcessed
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package request
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/textract"
+)
+
+// invoiceHeaderUpdates arma las columnas de cabecera del documento; un reproceso las reemplaza completas
+func invoiceHeaderUpdates(header textract.InvoiceHeader) map[string]any {
+	return map[string]any{
+		"supplier_name":   header.SupplierName,
+		"supplier_tax_id": header.SupplierTaxID,
+		"invoice_folio":   header.Folio,
+		"issue_date":      header.IssueDate,
+		"currency":        header.Currency,
+		"net_amount":      header.Net,
+		"tax_amount":      header.Tax,
+		"total_amount":    header.Total,
+	}
+}
+
+func toInvoiceHeaderDto(document models.Documents) *dto.InvoiceHeaderDto {
+	header := dto.InvoiceHeaderDto{
+		SupplierName:  document.SupplierName,
+		SupplierTaxId: document.SupplierTaxID,
+		Folio:         document.InvoiceFolio,
+		IssueDate:     document.IssueDate,
+		Currency:      document.Currency,
+		Net:           document.NetAmount,
+		Tax:           document.TaxAmount,
+		Total:         document.TotalAmount,
+	}
+	if header == (dto.InvoiceHeaderDto{}) {
+		return nil
+	}
+	return &header
+}
+
+// requestInvoice toma la cabecera del primer documento que tenga folio (la factura), o del primero con datos
+func requestInvoice(documents []models.Documents) *dto.InvoiceHeaderDto {
+	var first *dto.InvoiceHeaderDto
+	for _, document := range documents {
+		header := toInvoiceHeaderDto(document)
+		if header == nil {
+			continue
+		}
+		if header.Folio != "" {
+			return header
+		}
+		if first == nil {
+			first = header
+		}
+	}
+	return first
+}
+
+// SupplierSummary agrupa por proveedor (el de la cabecera de la factura) las solicitudes del período que no fueron
+// rechazadas ni canceladas, con las unidades de sus movimientos
+func (r requestService) SupplierSummary(ctx context.Context, clientAccountId uuid.UUID, from, to time.Time, limit int) ([]dto.SupplierSummaryDto, error) {
+	var results []dto.SupplierSummaryDto
+
+	err := r.db.WithContext(ctx).Raw(`
+SELECT d.supplier_name AS supplier,
+       COUNT(DISTINCT r.id) AS requests,
+       COALESCE(SUM(m.count), 0) AS units
+FROM request r
+JOIN LATERAL (
+    SELECT supplier_name
+    FROM documents
+    WHERE request_id = r.id AND supplier_name IS NOT NULL AND supplier_name <> ''
+    ORDER BY created_at
+    LIMIT 1
+) d ON true
+LEFT JOIN movement m ON m.request_id = r.id
+WHERE r.client_account_id = ?
+  AND r.create_at BETWEEN ? AND ?
+  AND r.status NOT IN (?, ?)
+GROUP BY d.supplier_name
+ORDER BY units DESC
+LIMIT ?`,
+		clientAccountId, from, to, models.RequestStatusRejected, models.RequestStatusCancelled, limit,
+	).Scan(&results).Error
+
+	return results, err
+}
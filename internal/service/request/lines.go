@@ -0,0 +1,176 @@
+package request
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/extractor"
+	"gorm.io/gorm"
+)
+
+// lineItem es una línea extraída junto con los documentos de donde salió.
+// ProductID viene informado cuando el producto ya se conoce (solicitudes manuales).
+// DocumentsDisagree indica que los documentos traían cantidades distintas y se tomó la mayor.
+type lineItem struct {
+	extractor.Line
+	Sources           []uuid.UUID
+	ProductID         uuid.UUID
+	DocumentsDisagree bool
+}
+
+// lineKey identifica el mismo producto entre documentos: primer SKU normalizado o, si no hay, el nombre
+func lineKey(product bedrock.ProductResponse) string {
+	for _, sku := range product.SKUs {
+		if key := normalizeSKU(sku); key != "" {
+			return "sku:" + key
+		}
+	}
+	return "name:" + normalizeSKU(product.Name)
+}
+
+// mergeLines une las líneas de todos los documentos de una solicitud.
+// Dentro de un documento las líneas repetidas se suman; entre documentos (factura + guía de despacho)
+// se asume que describen la misma mercadería y se toma la mayor cantidad en vez de duplicarla.
+func mergeLines(byDocument map[uuid.UUID][]extractor.Line, order []uuid.UUID) []lineItem {
+	merged := make([]lineItem, 0)
+	index := make(map[string]int)
+
+	for _, documentId := range order {
+		perDocument := make(map[string]extractor.Line)
+		keys := make([]string, 0)
+
+		for _, line := range byDocument[documentId] {
+			key := lineKey(line.ProductResponse)
+			if current, ok := perDocument[key]; ok {
+				current.Count += line.Count
+				perDocument[key] = combineSignals(current, line)
+				continue
+			}
+			perDocument[key] = line
+			keys = append(keys, key)
+		}
+
+		for _, key := range keys {
+			line := perDocument[key]
+			if i, ok := index[key]; ok {
+				if line.Count != merged[i].Count {
+					merged[i].DocumentsDisagree = true
+				}
+				count := max(line.Count, merged[i].Count)
+				merged[i].Line = combineSignals(merged[i].Line, line)
+				merged[i].Count = count
+				merged[i].Sources = append(merged[i].Sources, documentId)
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, lineItem{Line: line, Sources: []uuid.UUID{documentId}})
+		}
+	}
+
+	return merged
+}
+
+// combineSignals junta las señales de dos lecturas del mismo producto quedándose con la más desfavorable,
+// salvo el SKU, que basta con que aparezca en uno de los documentos
+func combineSignals(a extractor.Line, b extractor.Line) extractor.Line {
+	if b.OCRConfidence != nil && (a.OCRConfidence == nil || *b.OCRConfidence < *a.OCRConfidence) {
+		a.OCRConfidence = b.OCRConfidence
+	}
+	a.SkuSupplied = a.SkuSupplied || b.SkuSupplied
+	a.QuantityAmbiguous = a.QuantityAmbiguous || b.QuantityAmbiguous
+	a.SourceRowFound = a.SourceRowFound && b.SourceRowFound
+	return a
+}
+
+func saveRequestLine(tx *gorm.DB, requestId uuid.UUID, movementId uuid.UUID, productId uuid.UUID, line lineItem, score lineScore, candidates []skuCandidate) error {
+	skus, err := json.Marshal(line.SKUs)
+	if err != nil {
+		return err
+	}
+	sources, err := json.Marshal(line.Sources)
+	if err != nil {
+		return err
+	}
+	reasons, err := json.Marshal(score.Reasons)
+	if err != nil {
+		return err
+	}
+	var matched string
+	if len(candidates) > 0 {
+		raw, err := json.Marshal(candidates)
+		if err != nil {
+			return err
+		}
+		matched = string(raw)
+	}
+	confidence := score.Confidence
+
+	return tx.Create(&models.RequestLine{
+		ID:              uuid.New(),
+		RequestID:       requestId,
+		MovementID:      movementId,
+		ProductID:       productId,
+		Name:            line.Name,
+		Count:           line.Count,
+		Skus:            string(skus),
+		SourceDocuments: string(sources),
+		Confidence:      &confidence,
+		ReasonCodes:     string(reasons),
+		NeedsReview:     score.NeedsReview,
+		Candidates:      matched,
+	}).Error
+}
+
+func findRequestLines(db *gorm.DB, requestId uuid.UUID) (map[uuid.UUID]models.RequestLine, error) {
+	var lines []models.RequestLine
+	if err := db.Where("request_id = ?", requestId).Find(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	byMovement := make(map[uuid.UUID]models.RequestLine, len(lines))
+	for _, line := range lines {
+		byMovement[line.MovementID] = line
+	}
+	return byMovement, nil
+}
+
+func lineSources(line models.RequestLine) []uuid.UUID {
+	var sources []uuid.UUID
+	if line.SourceDocuments != "" {
+		_ = json.Unmarshal([]byte(line.SourceDocuments), &sources)
+	}
+	return sources
+}
+
+func lineReasons(line models.RequestLine) []string {
+	reasons := make([]string, 0)
+	if line.ReasonCodes != "" {
+		_ = json.Unmarshal([]byte(line.ReasonCodes), &reasons)
+	}
+	return reasons
+}
+
+func lineCandidates(line models.RequestLine) []dto.SkuCandidateDto {
+	var candidates []skuCandidate
+	if line.Candidates != "" {
+		_ = json.Unmarshal([]byte(line.Candidates), &candidates)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	items := make([]dto.SkuCandidateDto, 0, len(candidates))
+	for _, c := range candidates {
+		items = append(items, dto.SkuCandidateDto{
+			ProductId:   c.ProductID,
+			ProductName: c.ProductName,
+			Sku:         c.Sku,
+			Score:       c.Score,
+			Method:      c.Method,
+		})
+	}
+	return items
+}
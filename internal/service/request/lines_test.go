@@ -0,0 +1,117 @@
+package request
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/service/extractor"
+)
+
+// memorySource entrega documentos en memoria por su key
+type memorySource map[string]string
+
+func (m memorySource) GetDocument(key string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(m[key])), nil
+}
+
+func extractLocal(t *testing.T, source memorySource, keys ...string) (map[uuid.UUID][]extractor.Line, []uuid.UUID) {
+	t.Helper()
+	local := extractor.NewLocalExtractor(source)
+	byDocument := make(map[uuid.UUID][]extractor.Line)
+	order := make([]uuid.UUID, 0, len(keys))
+	for _, key := range keys {
+		doc := extractor.Document{ID: uuid.New(), Key: key, FileName: key}
+		result, err := local.Extract(context.Background(), doc, extractor.Options{})
+		if err != nil {
+			t.Fatalf("Extract(%s): %v", key, err)
+		}
+		byDocument[doc.ID] = result.Lines
+		order = append(order, doc.ID)
+	}
+	return byDocument, order
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func testMatcher() *skuMatcher {
+	tornillo, tuerca, tuerca2 := uuid.New(), uuid.New(), uuid.New()
+	return &skuMatcher{
+		entries: []catalogEntry{
+			{ProductID: tornillo, ProductName: "Tornillo 3mm", SkuID: ptr(uuid.New()), Sku: ptr("TOR-3MM")},
+			{ProductID: tuerca, ProductName: "Tuerca hexagonal M8", SkuID: ptr(uuid.New()), Sku: ptr("TUE-M8")},
+			{ProductID: tuerca2, ProductName: "Tuerca hexagonal M6", SkuID: ptr(uuid.New()), Sku: ptr("TUE-M6")},
+		},
+		aliases:   map[string]skuCandidate{},
+		threshold: 0.9,
+	}
+}
+
+func TestMergeLocalDocuments(t *testing.T) {
+	byDocument, order := extractLocal(t, memorySource{
+		"factura.txt": "# factura\nTornillo 3mm;10;TOR-3MM\nTornillo 3mm;5;tor-3mm\nTuerca;1.000\nArandela;2,5;ARA-1\nsin cantidad\n",
+		"guia.txt":    "Tornillo 3mm;12;TOR 3MM\n",
+	}, "factura.txt", "guia.txt")
+
+	lines := mergeLines(byDocument, order)
+	if len(lines) != 2 {
+		t.Fatalf("se esperaban 2 líneas, hay %d: %+v", len(lines), lines)
+	}
+
+	tornillo := lines[0]
+	if tornillo.Name != "Tornillo 3mm" || tornillo.Count != 15 {
+		t.Errorf("tornillo = %s x %d; se esperaba Tornillo 3mm x 15", tornillo.Name, tornillo.Count)
+	}
+	if !tornillo.DocumentsDisagree || len(tornillo.Sources) != 2 {
+		t.Errorf("tornillo debería venir de 2 documentos en desacuerdo: %+v", tornillo)
+	}
+
+	tuerca := lines[1]
+	if tuerca.Count != 1000 || tuerca.SkuSupplied || tuerca.DocumentsDisagree {
+		t.Errorf("tuerca = %+v; se esperaba 1000 sin SKU ni desacuerdo", tuerca)
+	}
+}
+
+func TestScoreLocalLines(t *testing.T) {
+	byDocument, order := extractLocal(t, memorySource{
+		"factura.txt": "Tornillo 3mm;10;TOR-3MM\nTornillo 3mm;5;TOR3MM\nTuerca hexagonal;4\nPerno;2;PER-9\n",
+		"guia.txt":    "Tornillo 3mm;12;TOR-3MM\n",
+	}, "factura.txt", "guia.txt")
+	matcher := testMatcher()
+
+	tests := []struct {
+		name      string
+		found     bool
+		ambiguous bool
+		method    string
+		reasons   []string
+		review    bool
+	}{
+		{"Tornillo 3mm", true, false, MatchExact, []string{ReasonDocumentsDisagree}, true},
+		{"Tuerca hexagonal", false, true, "", []string{ReasonSkuGenerated, ReasonSkuAmbiguous}, true},
+		{"Perno", false, false, "", []string{ReasonNewProduct}, false},
+	}
+
+	lines := mergeLines(byDocument, order)
+	if len(lines) != len(tests) {
+		t.Fatalf("se esperaban %d líneas, hay %d", len(tests), len(lines))
+	}
+	for i, tt := range tests {
+		line := lines[i]
+		if line.Name != tt.name {
+			t.Fatalf("línea %d = %s; se esperaba %s", i, line.Name, tt.name)
+		}
+
+		match := matcher.match(line.ProductResponse)
+		if match.Found != tt.found || match.Ambiguous != tt.ambiguous || (tt.found && match.Candidate.Method != tt.method) {
+			t.Errorf("%s: match = %+v", tt.name, match)
+		}
+
+		score := scoreLine(line, match, 0.85)
+		if strings.Join(score.Reasons, ",") != strings.Join(tt.reasons, ",") || score.NeedsReview != tt.review {
+			t.Errorf("%s: score = %+v; se esperaban motivos %v y revisión %v", tt.name, score, tt.reasons, tt.review)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"github.com/stock-ahora/api-stock/internal/service/extractor"
+	"gorm.io/gorm"
+)
+
+// CreateManual crea una solicitud desde líneas cargadas a mano (correcciones, ventas de mostrador).
+// No pasa por S3/Textract/Bedrock: queda pendiente de revisión igual que una solicitud procesada.
+func (r requestService) CreateManual(ctx context.Context, clientAccountId uuid.UUID, actor string, requestDto dto.CreateManualRequestDto) (models.Request, error) {
+	if len(requestDto.Items) == 0 {
+		return models.Request{}, fmt.Errorf("%w: la solicitud no tiene líneas", ErrInvalidManualRequest)
+	}
+	if requestDto.Type != "in" && requestDto.Type != "out" {
+		return models.Request{}, fmt.Errorf("%w: el tipo debe ser 'in' u 'out'", ErrInvalidManualRequest)
+	}
+	typeStatus := dto.ParseTypeStatus(requestDto.Type)
+
+	request := models.Request{
+		ID:              uuid.New(),
+		Status:          models.RequestCreated,
+		ClientAccountID: clientAccountId,
+		MovementTypeId:  typeStatus.MovementTypeId(),
+		CreatedAt:       time.Now(),
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lines, err := resolveManualItems(tx, clientAccountId, requestDto.Items)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(&request).Error; err != nil {
+			return err
+		}
+		if err := recordStatus(tx, request.ID, "", models.RequestCreated, actor, "solicitud manual"); err != nil {
+			return err
+		}
+
+		if _, err := r.updateProduct(ctx, lines, tx, typeStatus.Sign(), clientAccountId, request.ID); err != nil {
+			return err
+		}
+
+		shortID := strings.Split(request.ID.String(), "-")[0]
+		err = tx.Exec(
+			"INSERT INTO public.notification (message, type, is_read, date) VALUES (?, ?, ?, ?)",
+			"Solicitud manual pendiente de revisión "+shortID,
+			"request",
+			false,
+			time.Now(),
+		).Error
+		if err != nil {
+			return err
+		}
+
+		return transition(tx, request.ID, models.RequestCreated, models.RequestStatusPending, actor, "solicitud manual")
+	})
+	if err != nil {
+		return models.Request{}, err
+	}
+
+	request.Status = models.RequestStatusPending
+	return request, nil
+}
+
+// resolveManualItems valida que cada producto pertenezca a la cuenta cliente y lo convierte en línea
+func resolveManualItems(tx *gorm.DB, clientAccountId uuid.UUID, items []dto.ManualItemDto) ([]lineItem, error) {
+	lines := make([]lineItem, 0, len(items))
+
+	for i, item := range items {
+		if item.Count <= 0 {
+			return nil, fmt.Errorf("%w: la línea %d tiene cantidad %d", ErrInvalidManualRequest, i+1, item.Count)
+		}
+
+		var product models.Product
+		var err error
+		switch {
+		case item.ProductId != uuid.Nil:
+			err = tx.First(&product, "id = ? AND client_account_id = ?", item.ProductId, clientAccountId).Error
+		case strings.TrimSpace(item.Sku) != "":
+			err = tx.Joins("JOIN sku ON sku.product_id = product.id").
+				Where("product.client_account_id = ?", clientAccountId).
+				Where("regexp_replace(upper(sku.name_sku), '[^A-Z0-9]', '', 'g') = ?", normalizeSKU(item.Sku)).
+				First(&product).Error
+		default:
+			return nil, fmt.Errorf("%w: la línea %d requiere productId o sku", ErrInvalidManualRequest, i+1)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: línea %d", ErrUnknownProduct, i+1)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		skus := make([]string, 0, 1)
+		if item.Sku != "" {
+			skus = append(skus, item.Sku)
+		}
+
+		lines = append(lines, lineItem{
+			Line: extractor.Line{
+				ProductResponse: bedrock.ProductResponse{
+					Name:  product.Name,
+					Count: item.Count,
+					SKUs:  skus,
+				},
+				SkuSupplied:    true,
+				SourceRowFound: true,
+			},
+			ProductID: product.ID,
+		})
+	}
+
+	return lines, nil
+}
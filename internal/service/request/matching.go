@@ -0,0 +1,231 @@
+package request
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"gorm.io/gorm"
+)
+
+// Métodos con que un candidato coincidió con la línea, de más a menos confiable
+const (
+	MatchAlias      = "alias"
+	MatchExact      = "exact"
+	MatchNormalized = "normalized"
+	MatchFuzzy      = "fuzzy"
+	MatchName       = "name"
+)
+
+const (
+	// minCandidateScore es el puntaje bajo el cual un producto ni siquiera se muestra como candidato
+	minCandidateScore = 0.5
+	// ambiguityMargin es la diferencia mínima entre el mejor candidato y el segundo para enlazar sin revisión
+	ambiguityMargin = 0.05
+	maxCandidates   = 3
+
+	// los métodos menos confiables pesan menos que una coincidencia exacta; un alias aprendido
+	// para el proveedor de la factura vale como exacto y uno de la cuenta casi
+	accountAliasWeight = 0.99
+	normalizedWeight   = 0.97
+	fuzzyWeight        = 0.95
+	nameWeight         = 0.9
+)
+
+// skuCandidate es un producto de la cuenta que podría ser el de la línea, con el SKU que lo hizo coincidir
+type skuCandidate struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	SkuID       uuid.UUID `json:"sku_id,omitempty"`
+	Sku         string    `json:"sku,omitempty"`
+	Score       float64   `json:"score"`
+	Method      string    `json:"method"`
+}
+
+// catalogEntry es un SKU activo de la cuenta con su producto; los productos sin SKU vienen con SkuID nulo
+type catalogEntry struct {
+	ProductID   uuid.UUID
+	ProductName string
+	SkuID       *uuid.UUID
+	Sku         *string
+}
+
+// skuMatcher resuelve las líneas contra el catálogo de una sola cuenta cliente. Nunca mira productos de otras cuentas.
+type skuMatcher struct {
+	entries   []catalogEntry
+	aliases   map[string]skuCandidate
+	threshold float64
+}
+
+// newSkuMatcher carga una vez el catálogo y los alias de la cuenta para resolver todas las líneas de la solicitud;
+// los productos archivados quedan fuera y de los alias solo se usan los de la cuenta y los del proveedor de la solicitud
+func newSkuMatcher(tx *gorm.DB, clientAccountId uuid.UUID, requestId uuid.UUID, threshold float64) (*skuMatcher, error) {
+	var entries []catalogEntry
+	err := tx.Table("product").
+		Select("product.id AS product_id, product.name AS product_name, sku.id AS sku_id, sku.name_sku AS sku").
+		Joins("LEFT JOIN sku ON sku.product_id = product.id AND sku.status").
+		Where("product.client_account_id = ? AND product.status IS DISTINCT FROM ?", clientAccountId, models.ProductStatusArchived).
+		Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	supplier, err := requestSupplier(tx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	query := tx.Where("client_account_id = ?", clientAccountId)
+	if supplier != nil {
+		query = query.Where("supplier_tax_id IS NULL OR supplier_tax_id = ?", *supplier)
+	} else {
+		query = query.Where("supplier_tax_id IS NULL")
+	}
+	var aliases []models.SkuAlias
+	if err := query.Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[uuid.UUID]string, len(entries))
+	for _, entry := range entries {
+		names[entry.ProductID] = entry.ProductName
+	}
+	byAlias := make(map[string]skuCandidate, len(aliases))
+	for _, alias := range aliases {
+		if _, ok := names[alias.ProductID]; !ok {
+			// el producto del alias está archivado
+			continue
+		}
+		candidate := skuCandidate{
+			ProductID:   alias.ProductID,
+			ProductName: names[alias.ProductID],
+			Sku:         alias.Alias,
+			Score:       accountAliasWeight,
+			Method:      MatchAlias,
+		}
+		if alias.SkuID != nil {
+			candidate.SkuID = *alias.SkuID
+		}
+		if alias.SupplierTaxID != nil {
+			candidate.Score = 1
+		}
+		// el alias del proveedor gana al de la cuenta
+		if current, ok := byAlias[alias.AliasNormalized]; !ok || candidate.Score > current.Score {
+			byAlias[alias.AliasNormalized] = candidate
+		}
+	}
+
+	return &skuMatcher{entries: entries, aliases: byAlias, threshold: threshold}, nil
+}
+
+// match puntúa cada producto de la cuenta contra los SKUs y el nombre leídos y devuelve los mejores candidatos.
+// Found solo si el mejor supera el umbral y se distingue del segundo; si no, con candidatos la línea es ambigua.
+func (m *skuMatcher) match(product bedrock.ProductResponse) skuMatch {
+	best := make(map[uuid.UUID]skuCandidate)
+	for _, read := range append(append([]string{}, product.SKUs...), product.Name) {
+		if alias, ok := m.aliases[normalizeSKU(read)]; ok {
+			if current, ok := best[alias.ProductID]; !ok || alias.Score > current.Score {
+				best[alias.ProductID] = alias
+			}
+		}
+	}
+	for _, entry := range m.entries {
+		candidate := scoreEntry(product, entry)
+		if current, ok := best[entry.ProductID]; !ok || candidate.Score > current.Score {
+			best[entry.ProductID] = candidate
+		}
+	}
+
+	candidates := make([]skuCandidate, 0, len(best))
+	for _, candidate := range best {
+		if candidate.Score >= minCandidateScore {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].ProductName < candidates[j].ProductName
+	})
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+
+	if len(candidates) == 0 {
+		return skuMatch{}
+	}
+	top := candidates[0]
+	// un alias lo enseñó un revisor: no hace falta que se distinga de los demás candidatos
+	distinct := len(candidates) == 1 || top.Method == MatchAlias || top.Score-candidates[1].Score >= ambiguityMargin
+	if top.Score >= m.threshold && distinct {
+		return skuMatch{Found: true, Score: top.Score, Candidate: top, Candidates: candidates}
+	}
+	return skuMatch{Ambiguous: true, Candidates: candidates}
+}
+
+// scoreEntry devuelve la mejor coincidencia de la línea con una entrada del catálogo
+func scoreEntry(product bedrock.ProductResponse, entry catalogEntry) skuCandidate {
+	candidate := skuCandidate{ProductID: entry.ProductID, ProductName: entry.ProductName}
+	consider := func(score float64, method string) {
+		score = math.Round(score*100) / 100
+		if score > candidate.Score {
+			candidate.Score = score
+			candidate.Method = method
+		}
+	}
+
+	if entry.Sku != nil {
+		candidate.SkuID = *entry.SkuID
+		candidate.Sku = *entry.Sku
+		stored := normalizeSKU(*entry.Sku)
+
+		for _, sku := range product.SKUs {
+			read := normalizeSKU(sku)
+			switch {
+			case read == "" || stored == "":
+				continue
+			case strings.EqualFold(strings.TrimSpace(sku), strings.TrimSpace(*entry.Sku)):
+				consider(1, MatchExact)
+			case read == stored:
+				consider(normalizedWeight, MatchNormalized)
+			default:
+				consider(fuzzyWeight*max(trigramSimilarity(read, stored), skuSimilarity(read, stored)), MatchFuzzy)
+			}
+		}
+	}
+
+	if name := normalizeSKU(product.Name); name != "" {
+		consider(nameWeight*trigramSimilarity(name, normalizeSKU(entry.ProductName)), MatchName)
+	}
+	return candidate
+}
+
+// trigramSimilarity es la similitud de trigramas al estilo de pg_trgm: trigramas compartidos sobre el total
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+	if s == "" {
+		return nil
+	}
+	padded := []rune("  " + strings.ToLower(s) + " ")
+	set := make(map[string]bool, len(padded))
+	for i := 0; i+3 <= len(padded); i++ {
+		set[string(padded[i:i+3])] = true
+	}
+	return set
+}
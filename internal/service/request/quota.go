@@ -0,0 +1,19 @@
+package request
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/service/extractor"
+)
+
+// checkOCRQuota rechaza con *usage.QuotaExceededError si la cuenta agotó su cuota del mes y algún documento
+// pasa por Textract y el LLM; las planillas se leen directo y no consumen cuota
+func (r requestService) checkOCRQuota(ctx context.Context, clientAccountId uuid.UUID, documents []extractor.Document) error {
+	for _, document := range documents {
+		if !extractor.IsSpreadsheet(document) {
+			return r.usage.CheckQuota(ctx, clientAccountId)
+		}
+	}
+	return nil
+}
@@ -8,183 +8,258 @@ import (
 	"time"
 	"unicode"
 
-	"github.com/aws/smithy-go"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/stock-ahora/api-stock/internal/config"
 	"github.com/stock-ahora/api-stock/internal/dto"
 	"github.com/stock-ahora/api-stock/internal/models"
-	"github.com/stock-ahora/api-stock/internal/service/bedrock"
 	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+	"github.com/stock-ahora/api-stock/internal/service/extractor"
+	"github.com/stock-ahora/api-stock/internal/service/prompt"
 	"github.com/stock-ahora/api-stock/internal/service/s3"
-	"github.com/stock-ahora/api-stock/internal/service/textract"
+	"github.com/stock-ahora/api-stock/internal/service/stock"
+	"github.com/stock-ahora/api-stock/internal/service/usage"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RequestService interface {
-	List(ctx context.Context, clientAccountId uuid.UUID, page, size int) (dto.Page[dto.RequestListDto], error)
+	List(ctx context.Context, clientAccountId uuid.UUID, folio string, page, size int) (dto.Page[dto.RequestListDto], error)
 	Create(*dto.CreateRequestDto, context.Context) (models.Request, error)
-	Get(ctx context.Context, uuid uuid.UUID) (dto.RequestDto, error)
-	Confirm(clientAccountId uuid.UUID, RequestPatch dto.RequestPatch) error
+	CreateManual(ctx context.Context, clientAccountId uuid.UUID, actor string, requestDto dto.CreateManualRequestDto) (models.Request, error)
+	Get(ctx context.Context, clientAccountId uuid.UUID, requestId uuid.UUID) (dto.RequestDto, error)
+	Confirm(clientAccountId uuid.UUID, actor string, RequestPatch dto.RequestPatch) error
+	Reprocess(ctx context.Context, clientAccountId uuid.UUID, actor string, requestId uuid.UUID, options dto.ReprocessRequestDto) error
+	ColumnMapping(ctx context.Context, clientAccountId uuid.UUID) (dto.ColumnMappingDto, error)
+	SaveColumnMapping(ctx context.Context, clientAccountId uuid.UUID, mapping dto.ColumnMappingDto) (dto.ColumnMappingDto, error)
+	SkuAliases(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID) ([]dto.SkuAliasDto, error)
+	SaveSkuAlias(ctx context.Context, clientAccountId uuid.UUID, actor string, alias dto.CreateSkuAliasDto) (dto.SkuAliasDto, error)
+	DeleteSkuAlias(ctx context.Context, clientAccountId uuid.UUID, aliasId uuid.UUID) error
+	Attempts(ctx context.Context, clientAccountId uuid.UUID, requestId uuid.UUID) ([]dto.RequestAttemptDto, error)
+	Cancel(ctx context.Context, clientAccountId uuid.UUID, actor string, requestId uuid.UUID, reason string) error
+	History(ctx context.Context, clientAccountId uuid.UUID, requestId uuid.UUID) ([]dto.RequestStatusHistoryDto, error)
+	SupplierSummary(ctx context.Context, clientAccountId uuid.UUID, from, to time.Time, limit int) ([]dto.SupplierSummaryDto, error)
 	//todo: agregar metodo para confirmar la request y agregar en el open api el metodo igual
 	//todo: agregar metodo para modificar la request
-	Process(ctx context.Context, requestId uuid.UUID, clientAccountId uuid.UUID, typeIngress int) error
-	ProcessCtx(ctx context.Context, requestId uuid.UUID, clientAccountId uuid.UUID, typeIngress int) interface{}
+	Process(ctx context.Context, evt eventservice.RequestProcessEvent) error
+	ProcessCtx(ctx context.Context, evt eventservice.RequestProcessEvent) error
+	RequeueStalled(ctx context.Context, maxFailures int) (int, error)
 }
 
 type requestService struct {
 	db          *gorm.DB
 	db_estrella *gorm.DB
-	s3Svc       *s3.S3Svc
+	s3Svc       s3.Storage
 	eventSvc    *eventservice.MQPublisher
-	textract    *textract.TextractService
+	extractor   extractor.Extractor
+	prompts     prompt.Registry
+	usage       usage.Service
+	settings    Settings
 }
 
-func NewRequestService(db *gorm.DB, s3Svc *s3.S3Svc, eventSvc *eventservice.MQPublisher, textract *textract.TextractService, db_estrella *gorm.DB) RequestService {
-	return &requestService{db: db, db_estrella: db_estrella, s3Svc: s3Svc, eventSvc: eventSvc, textract: textract}
+// Settings agrupa la configuración del procesamiento de solicitudes
+type Settings struct {
+	// DefaultModel es el modelo configurado, usado cuando el evento no pide otro
+	DefaultModel string
+	// ReviewThreshold es la confianza bajo la cual una línea queda marcada para revisión obligatoria
+	ReviewThreshold float64
+	// MatchThreshold es el puntaje desde el cual una línea se enlaza sola a un producto existente de la cuenta
+	MatchThreshold float64
 }
 
-func (r requestService) Confirm(clientAccountId uuid.UUID, RequestPatch dto.RequestPatch) error {
-	var request models.Request
-
-	result := r.db.First(&request, "id = ? AND client_account_id = ?", RequestPatch.Id, clientAccountId)
-	if result.Error != nil {
-		return result.Error
-	}
+func NewRequestService(db *gorm.DB, s3Svc s3.Storage, eventSvc *eventservice.MQPublisher, extractor extractor.Extractor, prompts prompt.Registry, usageSvc usage.Service, db_estrella *gorm.DB, settings Settings) RequestService {
+	return &requestService{db: db, db_estrella: db_estrella, s3Svc: s3Svc, eventSvc: eventSvc, extractor: extractor, prompts: prompts, usage: usageSvc, settings: settings}
+}
 
-	request.Status = models.RequestStatusApproved
+func (r requestService) Confirm(clientAccountId uuid.UUID, actor string, RequestPatch dto.RequestPatch) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var request models.Request
 
-	for _, m := range RequestPatch.Movements {
-		if m.Deleted {
-			deleteMovement(m, r.db, r.db_estrella)
-			continue
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&request, "id = ? AND client_account_id = ?", RequestPatch.Id, clientAccountId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRequestNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !request.Status.CanTransitionTo(models.RequestStatusApproved) {
+			return &TransitionError{RequestID: request.ID, From: request.Status, To: models.RequestStatusApproved}
 		}
-		updateMovement(m, r.db, r.db_estrella)
 
-	}
+		// las líneas marcadas para revisión tienen que venir en la confirmación (editadas, eliminadas o aceptadas tal cual)
+		flagged, err := pendingReview(tx, request.ID)
+		if err != nil {
+			return err
+		}
+		reviewed := make(map[uuid.UUID]bool, len(RequestPatch.Movements))
+		for _, m := range RequestPatch.Movements {
+			reviewed[m.Id] = true
+		}
+		missing := make([]uuid.UUID, 0)
+		for _, id := range flagged {
+			if !reviewed[id] {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			return &ReviewRequiredError{RequestID: request.ID, MovementIDs: missing}
+		}
 
-	result = r.db.Model(&request).Update("status", models.RequestStatusApproved)
+		for _, m := range RequestPatch.Movements {
+			var adjustment eventservice.ProductAdjustEvent
+			switch {
+			case m.Deleted:
+				adjustment, err = deleteMovement(tx, request.ID, m)
+			case m.ProductId != uuid.Nil:
+				adjustment, err = reassignMovement(tx, request, actor, m)
+			default:
+				adjustment, err = updateMovement(tx, request.ID, m)
+			}
+			if err != nil {
+				return &ConfirmError{RequestID: request.ID, MovementID: m.Id, Err: err}
+			}
 
-	if result.Error != nil {
-		return result.Error
-	}
+			// el esquema estrella vive en otra base, se actualiza vía ETL desde el outbox
+			if err := r.eventSvc.EnqueueProductAdjust(tx, adjustment); err != nil {
+				return err
+			}
+		}
 
-	return nil
+		return transition(tx, request.ID, request.Status, models.RequestStatusApproved, actor, "confirmación de movimientos")
+	})
 }
 
-func updateMovement(m dto.MovementsPatch, db *gorm.DB, dbEstrella *gorm.DB) {
-
+func findRequestMovement(tx *gorm.DB, requestId uuid.UUID, movementId uuid.UUID) (models.Movement, error) {
 	var movement models.Movement
 
-	result := db.First(&movement, "id = ?", m.Id)
-	if result.Error != nil {
-		log.Println("Error obteniendo movimiento:", result.Error)
-		return
+	err := tx.First(&movement, "id = ? AND request_id = ?", movementId, requestId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return movement, ErrMovementNotFound
 	}
+	return movement, err
+}
 
-	delta := dto.GetTypeMovementForDeltaUpdate(m.TypeMovementId) * (m.Count - movement.Count)
+func updateMovement(tx *gorm.DB, requestId uuid.UUID, m dto.MovementsPatch) (eventservice.ProductAdjustEvent, error) {
 
-	err := db.Exec("UPDATE movement SET count = ? WHERE id = ?", m.Count, m.Id).Error
+	movement, err := findRequestMovement(tx, requestId, m.Id)
 	if err != nil {
-		log.Printf("Error actualizando movimiento %v: %v", m.ProductId, err)
+		return eventservice.ProductAdjustEvent{}, err
 	}
 
-	err = db.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", delta, m.ProductId).Error
-	if err != nil {
-		log.Printf("Error actualizando movimiento %v: %v", m.ProductId, err)
+	delta := dto.GetTypeMovementForDeltaUpdate(movement.MovementTypeID) * (m.Count - movement.Count)
+
+	if err := tx.Exec("UPDATE movement SET count = ? WHERE id = ?", m.Count, movement.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("actualizando movimiento: %w", err)
+	}
+	if err := tx.Exec("UPDATE request_line SET count = ? WHERE movement_id = ?", m.Count, movement.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("actualizando línea del movimiento: %w", err)
 	}
 
-	var productUpdate models.Product
+	if err := tx.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", delta, movement.ProductID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("actualizando stock del producto %s: %w", movement.ProductID, err)
+	}
 
-	result = db.First(&productUpdate, "id = ?", m.ProductId)
+	var productUpdate models.Product
+	if err := tx.First(&productUpdate, "id = ?", movement.ProductID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("obteniendo producto %s: %w", movement.ProductID, err)
+	}
 
 	var message = "Producto con peligro de stock " + productUpdate.Name + " Stock actual: " + fmt.Sprintf("%d", productUpdate.Stock)
 	var now = time.Now()
-	db.Exec(
+	err = tx.Exec(
 		"INSERT INTO public.notification (message, type, is_read, date) VALUES (?, ?, ?, ?)",
 		message,
 		"product",
 		false,
 		now,
-	)
-
-	err = dbEstrella.Exec("UPDATE dim_producto SET stock = stock + ? WHERE producto_uuid = ?", delta, m.ProductId).Error
+	).Error
 	if err != nil {
-		log.Printf("Error actualizando movimiento %v: %v", m.ProductId, err)
-	}
-
-	err = dbEstrella.Exec("UPDATE fact_product_movement SET cantidad = cantidad + ? WHERE movimiento_uuid = ?", m.Count, m.ProductId).Error
-	if err != nil {
-		log.Printf("Error actualizando movimiento %v: %v", m.ProductId, err)
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("creando notificación: %w", err)
 	}
 
+	return eventservice.ProductAdjustEvent{
+		ProductoID:   movement.ProductID.String(),
+		MovimientoID: movement.ID.String(),
+		DeltaStock:   delta,
+		Cantidad:     m.Count,
+	}, nil
 }
 
-func deleteMovement(m dto.MovementsPatch, db *gorm.DB, dbEstrella *gorm.DB) {
-
-	var movement models.Movement
+func deleteMovement(tx *gorm.DB, requestId uuid.UUID, m dto.MovementsPatch) (eventservice.ProductAdjustEvent, error) {
 
-	result := db.First(&movement, "id = ?", m.Id)
-	if result.Error != nil {
-		log.Println("Error obteniendo movimiento:", result.Error)
-		return
+	movement, err := findRequestMovement(tx, requestId, m.Id)
+	if err != nil {
+		return eventservice.ProductAdjustEvent{}, err
 	}
 
-	result = db.Exec("DELETE FROM request_per_product WHERE movement_id = ?", m.Id)
-	if result.Error != nil {
-		log.Println("Error eliminando:", result.Error)
+	if err := tx.Exec("DELETE FROM request_per_product WHERE movement_id = ?", movement.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("eliminando relación del movimiento: %w", err)
 	}
 
-	result = db.Exec("DELETE FROM movement WHERE id = ?", m.Id)
-	if result.Error != nil {
-		log.Println("Error eliminando:", result.Error)
+	if err := tx.Exec("DELETE FROM movement WHERE id = ?", movement.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("eliminando movimiento: %w", err)
 	}
-
-	delta := dto.GetTypeMovementForDeltaDelete(m.TypeMovementId) * movement.Count
-
-	err := db.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", delta, m.ProductId).Error
-	if err != nil {
-		log.Printf("Error actualizando movimiento %v: %v", m.ProductId, err)
+	// la línea se conserva para la revisión, pero ya no aporta stock
+	if err := tx.Exec("UPDATE request_line SET count = 0 WHERE movement_id = ?", movement.ID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("actualizando línea del movimiento: %w", err)
 	}
 
-	err = dbEstrella.Exec("UPDATE dim_producto SET stock = stock + ? WHERE producto_uuid = ?", delta, m.ProductId).Error
-	if err != nil {
-		log.Printf("Error actualizando movimiento %v: %v", m.ProductId, err)
-	}
+	delta := dto.GetTypeMovementForDeltaDelete(movement.MovementTypeID) * movement.Count
 
-	err = dbEstrella.Exec("UPDATE fact_product_movement SET cantidad = 0 WHERE movimiento_uuid = ?", m.ProductId).Error
-	if err != nil {
-		log.Printf("Error actualizando movimiento %v: %v", m.ProductId, err)
+	if err := tx.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", delta, movement.ProductID).Error; err != nil {
+		return eventservice.ProductAdjustEvent{}, fmt.Errorf("actualizando stock del producto %s: %w", movement.ProductID, err)
 	}
 
+	return eventservice.ProductAdjustEvent{
+		ProductoID:   movement.ProductID.String(),
+		MovimientoID: movement.ID.String(),
+		DeltaStock:   delta,
+		Cantidad:     0,
+	}, nil
 }
 
-func (r requestService) ProcessCtx(ctx context.Context, requestId uuid.UUID, clientAccountId uuid.UUID, typeIngress int) interface{} {
+// ProcessCtx procesa el evento de forma idempotente: las reentregas se confirman sin volver a ejecutar Textract ni Bedrock.
+func (r requestService) ProcessCtx(ctx context.Context, evt eventservice.RequestProcessEvent) error {
 
-	err := r.Process(ctx, requestId, clientAccountId, typeIngress)
+	processed, err := r.isEventProcessed(ctx, evt)
 	if err != nil {
+		return err
+	}
+	if processed {
+		log.Printf("Evento %s de la solicitud %s ya procesado, se descarta", evt.EventID, evt.RequestID)
+		return nil
+	}
+
+	err = r.Process(ctx, evt)
+	if errors.Is(err, ErrRequestAlreadyProcessed) {
+		log.Printf("Solicitud %s ya procesada, se descarta el evento %s", evt.RequestID, evt.EventID)
 		return nil
 	}
-	return nil
+	return err
 }
 
-func (r requestService) List(ctx context.Context, clientAccountId uuid.UUID, page, size int) (dto.Page[dto.RequestListDto], error) {
+// List pagina las solicitudes del cliente; con folio filtra las que tengan un documento con ese número de factura
+func (r requestService) List(ctx context.Context, clientAccountId uuid.UUID, folio string, page, size int) (dto.Page[dto.RequestListDto], error) {
 
 	db := config.GetDB()
 
 	offset := (page - 1) * size
 
+	query := db.Model(&models.Request{}).Where("client_account_id = ?", clientAccountId)
+	if folio != "" {
+		query = query.Where("id IN (SELECT request_id FROM documents WHERE invoice_folio = ?)", folio)
+	}
+
 	var total int64
-	if err := db.Model(&models.Request{}).
-		Where("client_account_id = ?", clientAccountId).
-		Count(&total).Error; err != nil {
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		return dto.Page[dto.RequestListDto]{}, err
 	}
 
 	// DATA
 	var requests []models.Request
-	if err := db.
-		Where("client_account_id = ?", clientAccountId).
+	if err := query.
+		Preload("Documents").
 		Order("create_at DESC").
 		Limit(size).
 		Offset(offset).
@@ -194,14 +269,18 @@ func (r requestService) List(ctx context.Context, clientAccountId uuid.UUID, pag
 
 	items := make([]dto.RequestListDto, 0, len(requests))
 	for _, req := range requests {
-		items = append(items, dto.RequestListDto{
+		item := dto.RequestListDto{
 			ID:              req.ID,
 			RequestType:     dto.GetTypeMovementString(req.MovementTypeId),
 			Status:          req.Status,
 			CreatedAt:       req.CreatedAt,
 			UpdatedAt:       req.UpdatedAt,
 			ClientAccountId: req.ClientAccountID,
-		})
+		}
+		if invoice := requestInvoice(req.Documents); invoice != nil {
+			item.Folio = invoice.Folio
+		}
+		items = append(items, item)
 	}
 
 	return dto.Page[dto.RequestListDto]{
@@ -217,12 +296,6 @@ func (r requestService) Create(requestDto *dto.CreateRequestDto, ctx context.Con
 
 	db := config.GetDB()
 
-	key, err := r.s3Svc.DoHandleUpload(requestDto, "requests/")
-
-	if err != nil {
-		return models.Request{}, err
-	}
-
 	requestUuid := uuid.New()
 
 	request := models.Request{
@@ -233,49 +306,115 @@ func (r requestService) Create(requestDto *dto.CreateRequestDto, ctx context.Con
 		CreatedAt:       time.Now(),
 	}
 
-	document := models.Documents{
-		ID:        uuid.New(),
-		S3Path:    key,
-		RequestID: request.ID,
-		CreatedAt: time.Now(),
+	// hash -> nombre del archivo; el mismo archivo repetido en la carga se sube una sola vez
+	hashes := make(map[string]string, len(requestDto.Files))
+	files := make([]dto.UploadFile, 0, len(requestDto.Files))
+	fileHashes := make([]string, 0, len(requestDto.Files))
+	for _, file := range requestDto.Files {
+		hash, err := hashUpload(file)
+		if err != nil {
+			return models.Request{}, err
+		}
+		if _, repeated := hashes[hash]; repeated {
+			continue
+		}
+		hashes[hash] = file.FileName
+		files = append(files, file)
+		fileHashes = append(fileHashes, hash)
+	}
+
+	if !requestDto.Force {
+		if err := findDuplicate(db.WithContext(ctx), requestDto.ClientAccountId, hashes); err != nil {
+			return models.Request{}, err
+		}
 	}
 
-	err = db.Transaction(func(tx *gorm.DB) error {
+	pending := make([]extractor.Document, 0, len(files))
+	for _, file := range files {
+		pending = append(pending, extractor.Document{FileName: file.FileName, ContentType: file.FileType})
+	}
+	if err := r.checkOCRQuota(ctx, requestDto.ClientAccountId, pending); err != nil {
+		return models.Request{}, err
+	}
+
+	documents := make([]models.Documents, 0, len(files))
+	for i, file := range files {
+		key, err := r.s3Svc.DoHandleUpload(file, "requests/")
+		if err != nil {
+			r.discardUploads(documents)
+			return models.Request{}, err
+		}
+
+		documents = append(documents, models.Documents{
+			ID:          uuid.New(),
+			S3Path:      key,
+			FileName:    file.FileName,
+			ContentType: file.FileType,
+			ContentHash: fileHashes[i],
+			RequestID:   request.ID,
+			CreatedAt:   time.Now(),
+		})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !requestDto.Force {
+			if err := lockClientUploads(tx, requestDto.ClientAccountId); err != nil {
+				return err
+			}
+			if err := findDuplicate(tx, requestDto.ClientAccountId, hashes); err != nil {
+				return err
+			}
+		}
 		if err := tx.Debug().Create(&request).Error; err != nil {
 			return err
 		}
-		if err := tx.Debug().Create(&document).Error; err != nil {
+		for i := range documents {
+			if err := tx.Debug().Create(&documents[i]).Error; err != nil {
+				return err
+			}
+		}
+		if err := recordStatus(tx, request.ID, "", models.RequestCreated, SystemActor, fmt.Sprintf("%d documento(s) cargado(s)", len(documents))); err != nil {
 			return err
 		}
-		return nil
+
+		var event = createdRequestProcess(requestUuid, requestDto.ClientAccountId, requestDto.GetMovementToUpOrLessStock())
+		return r.eventSvc.EnqueueRequest(tx, event)
 	})
 
 	if err != nil {
+		// duplicado detectado con el bloqueo o insert fallido: los archivos ya subidos no quedan referenciados
+		r.discardUploads(documents)
 		return models.Request{}, err
 	}
 
-	var event = createdRequestProcess(requestUuid, requestDto.ClientAccountId, requestDto.GetMovementToUpOrLessStock())
+	return request, nil
+}
 
-	err = r.eventSvc.PublishRequest(event, ctx)
-	if err != nil {
-		return request, err
+// discardUploads elimina del almacenamiento los archivos de una carga que no llegó a guardarse
+func (r requestService) discardUploads(documents []models.Documents) {
+	for _, document := range documents {
+		if err := r.s3Svc.DeleteDocument(document.S3Path); err != nil {
+			log.Printf("No se pudo eliminar el archivo huérfano %s: %v", document.S3Path, err)
+		}
 	}
-
-	return request, nil
 }
 
-func (r requestService) Get(ctx context.Context, requestId uuid.UUID) (dto.RequestDto, error) {
-	db := config.GetDB()
+// Get devuelve la solicitud solo si es de la cuenta; trae la salida del modelo y los candidatos del catálogo
+func (r requestService) Get(ctx context.Context, clientAccountId uuid.UUID, requestId uuid.UUID) (dto.RequestDto, error) {
+	db := config.GetDB().WithContext(ctx)
 
 	var request models.Request
-	Request := db.Preload("Documents").First(&request, "id = ?", requestId)
-	if Request.Error != nil {
-		return dto.RequestDto{}, Request.Error
+	err := db.Preload("Documents").First(&request, "id = ? AND client_account_id = ?", requestId, clientAccountId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.RequestDto{}, ErrRequestNotFound
+	}
+	if err != nil {
+		return dto.RequestDto{}, err
 	}
 
 	var rpp []models.RequestPerProduct
 
-	err := db.
+	err = db.
 		Preload("Product").
 		Preload("Movement").
 		Where("request_id = ?", requestId).
@@ -284,10 +423,15 @@ func (r requestService) Get(ctx context.Context, requestId uuid.UUID) (dto.Reque
 		return dto.RequestDto{}, err
 	}
 
+	lines, err := findRequestLines(db, requestId)
+	if err != nil {
+		return dto.RequestDto{}, err
+	}
+
 	// mapear al DTO
 	movements := make([]dto.Movements, 0, len(rpp))
 	for _, x := range rpp {
-		movements = append(movements, dto.Movements{
+		movement := dto.Movements{
 			Id:             x.Movement.ID,
 			ProductId:      x.Product.ID,
 			Nombre:         x.Product.Name,
@@ -295,6 +439,28 @@ func (r requestService) Get(ctx context.Context, requestId uuid.UUID) (dto.Reque
 			Count:          x.Movement.Count,
 			CreatedAt:      x.Movement.CreatedAt,
 			UpdatedAt:      x.Movement.UpdatedAt,
+			Sources:        lineSources(lines[x.Movement.ID]),
+		}
+		if line, ok := lines[x.Movement.ID]; ok {
+			movement.Confidence = line.Confidence
+			movement.ReasonCodes = lineReasons(line)
+			movement.NeedsReview = line.NeedsReview
+			movement.Candidates = lineCandidates(line)
+		}
+		movements = append(movements, movement)
+	}
+
+	documents := make([]dto.DocumentDto, 0, len(request.Documents))
+	for _, d := range request.Documents {
+		documents = append(documents, dto.DocumentDto{
+			ID:            d.ID,
+			FileName:      d.FileName,
+			ContentHash:   d.ContentHash,
+			Invoice:       toInvoiceHeaderDto(d),
+			PromptVersion: d.PromptVersion,
+			ModelID:       d.ModelID,
+			RawOutput:     d.RawOutput,
+			CreatedAt:     d.CreatedAt,
 		})
 	}
 
@@ -305,6 +471,8 @@ func (r requestService) Get(ctx context.Context, requestId uuid.UUID) (dto.Reque
 		CreatedAt:       request.CreatedAt,
 		UpdatedAt:       request.UpdatedAt,
 		ClientAccountId: request.ClientAccountID,
+		Invoice:         requestInvoice(request.Documents),
+		Documents:       documents,
 		Movements:       movements,
 	}
 
@@ -320,10 +488,14 @@ func createdRequestProcess(requestId uuid.UUID, clientAccountId uuid.UUID, movem
 	}
 }
 
-func (r requestService) Process(ctx context.Context, requestId uuid.UUID, clientAccountId uuid.UUID, typeIngress int) error {
+func (r requestService) Process(ctx context.Context, evt eventservice.RequestProcessEvent) error {
 
 	db := config.GetDB()
 
+	requestId := evt.RequestID
+	clientAccountId := evt.ClientAccountId
+	typeIngress := evt.TypeIngress
+
 	log.Printf("Procesando solicitud con ID: %s", requestId)
 
 	var request models.Request
@@ -333,66 +505,168 @@ func (r requestService) Process(ctx context.Context, requestId uuid.UUID, client
 		log.Printf("Error al obtener la solicitud: %v", result.Error)
 		return result.Error
 	}
-	log.Printf("Procesando con ID: %s", requestId)
-	document := request.Documents[0]
+	if request.Status != models.RequestCreated {
+		return ErrRequestAlreadyProcessed
+	}
 
-	key := request.Documents[0].S3Path
-	bucket := r.s3Svc.GetBucket()
+	model := evt.Model
+	if model == "" {
+		model = r.settings.DefaultModel
+	}
+	promptVersion := evt.PromptVersion
+	resolved, promptErr := r.prompts.Resolve(ctx, prompt.UseCaseProductExtraction, request.ClientAccountID, evt.PromptVersion)
+	if promptErr == nil {
+		promptVersion = resolved.Version
+	} else if !errors.Is(promptErr, prompt.ErrPromptNotFound) {
+		return promptErr
+	}
 
-	resultTextract, idAnalizys, err := r.textract.AnalyzeInvoice(context.Background(), bucket, key)
+	attempt, err := startAttempt(db, requestId, model, promptVersion)
 	if err != nil {
-		var respErr *smithy.GenericAPIError
-		if errors.As(err, &respErr) {
-			fmt.Println("Code:", respErr.Code)
-			fmt.Println("Message:", respErr.Message)
-		} else {
-			fmt.Printf("unexpected error: %v\n", err)
-		}
+		return err
 	}
-	document.TextractId = *idAnalizys
-
-	log.Printf("Resultado de Textract para la solicitud %s:", requestId)
 
-	bedrockService := bedrock.NewBedrockService(ctx, bedrock.NOVA_PRO_AWS, "us-east-1")
+	log.Printf("Procesando con ID: %s", requestId)
+	if len(request.Documents) == 0 {
+		return rejectAttempt(db, evt, attempt, fmt.Errorf("la solicitud no tiene documentos"))
+	}
 
-	inputModel := textract.TablasToString(*resultTextract)
+	if promptErr != nil {
+		return rejectAttempt(db, evt, attempt, promptErr)
+	}
 
-	log.Printf("Input para Bedrock: %s", inputModel)
+	mapping, err := findColumnMapping(db, request.ClientAccountID)
+	if err != nil {
+		return err
+	}
+	options := extractor.Options{
+		Model:   model,
+		Prompt:  resolved.Template,
+		Mapping: mapping,
+		JobStarted: func(documentId uuid.UUID, jobId string) error {
+			return db.Model(&models.Documents{}).Where("id = ?", documentId).Update("textract_id", jobId).Error
+		},
+	}
+
+	byDocument := make(map[uuid.UUID][]extractor.Line, len(request.Documents))
+	order := make([]uuid.UUID, 0, len(request.Documents))
+	for _, document := range request.Documents {
+		products, err := r.extractDocument(ctx, db, request.ClientAccountID, attempt.ID, document, resolved.Version, options)
+		if err != nil {
+			err = fmt.Errorf("documento %s: %w", document.FileName, err)
+			if extractor.IsRetryable(err) {
+				// el job queda guardado en el documento y la próxima entrega lo retoma
+				if finishErr := finishAttempt(db, attempt, models.AttemptFailed, 0, nil, err); finishErr != nil {
+					log.Printf("Error registrando intento fallido %s: %v", attempt.ID, finishErr)
+				}
+				return err
+			}
+			return rejectAttempt(db, evt, attempt, err)
+		}
+		byDocument[document.ID] = products
+		order = append(order, document.ID)
+	}
 
-	resultBedrock, err := bedrockService.FormatProduct(ctx, inputModel, bedrock.ProductoPrompt)
+	lines := mergeLines(byDocument, order)
+	log.Printf("Líneas de la solicitud %s: %d", requestId, len(lines))
 
-	if err != nil {
-		log.Printf("Error al procesar con Bedrock: %v", err)
+	if len(lines) == 0 {
+		return rejectAttempt(db, evt, attempt, nil)
 	}
-	log.Printf("Resultado de Bedrock para la solicitud %s: %+v", requestId, resultBedrock)
 
-	if len(*resultBedrock) > 0 {
-		log.Printf("len resultBedrock: %d", len(*resultBedrock))
-		r.updateProduct(ctx, *resultBedrock, db, typeIngress, clientAccountId, requestId)
-		request.Status = models.RequestStatusPending
+	shortID := strings.Split(request.ID.String(), "-")[0]
 
-		shortID := strings.Split(request.ID.String(), "-")[0]
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := markProcessed(tx, evt, requestId, models.RequestStatusPending); err != nil {
+			return err
+		}
+		createdProducts, err := r.updateProduct(ctx, lines, tx, typeIngress, clientAccountId, requestId)
+		if err != nil {
+			return err
+		}
 
 		var now = time.Now()
 
-		r.db.Exec(
+		err = tx.Exec(
 			"INSERT INTO public.notification (message, type, is_read, date) VALUES (?, ?, ?, ?)",
 			"Solicitud de ingreso pendiente de revisión "+shortID,
 			"request",
 			false,
 			now,
-		)
-	} else {
-		log.Printf("len resultBedrock: %d", len(*resultBedrock))
-		request.Status = models.RequestStatusRejected
+		).Error
+		if err != nil {
+			return err
+		}
+
+		return finishAttempt(tx, attempt, models.AttemptSucceeded, len(lines), createdProducts, nil)
+	})
+	if err != nil && !errors.Is(err, ErrRequestAlreadyProcessed) {
+		if finishErr := finishAttempt(db, attempt, models.AttemptFailed, 0, nil, err); finishErr != nil {
+			log.Printf("Error registrando intento fallido %s: %v", attempt.ID, finishErr)
+		}
+	}
+	return err
+}
+
+// extractDocument pasa un documento por el extractor configurado y guarda el id del análisis, la cabecera de la factura,
+// el prompt, modelo y salida del LLM que la produjeron y las llamadas al LLM, también cuando la extracción falla.
+// Las páginas de Textract y los tokens de cada llamada al LLM quedan en el consumo de la cuenta.
+func (r requestService) extractDocument(ctx context.Context, db *gorm.DB, clientAccountId uuid.UUID, attemptId uuid.UUID, document models.Documents, promptVersion string, options extractor.Options) ([]extractor.Line, error) {
+	result, err := r.extractor.Extract(ctx, extractor.Document{
+		ID:            document.ID,
+		Bucket:        r.s3Svc.GetBucket(),
+		Key:           document.S3Path,
+		FileName:      document.FileName,
+		ContentType:   document.ContentType,
+		TextractJobID: document.TextractId,
+		ContentHash:   document.ContentHash,
+	}, options)
+
+	if saveErr := saveExtractions(db, attemptId, document.ID, result.LLMAttempts); saveErr != nil {
+		log.Printf("Error guardando las extracciones del documento %s: %v", document.ID, saveErr)
+	}
+	r.usage.RecordTextract(ctx, clientAccountId, &document.RequestID, result.TextractPages)
+	for _, llmAttempt := range result.LLMAttempts {
+		r.usage.RecordLLM(ctx, clientAccountId, &document.RequestID, usage.OperationProductExtraction, llmAttempt.Model, llmAttempt.Usage)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{}
+	if result.TextractJobID != "" {
+		updates["textract_id"] = result.TextractJobID
 	}
-	db.Save(&request)
-	return nil
+	if result.Header != nil {
+		for column, value := range invoiceHeaderUpdates(*result.Header) {
+			updates[column] = value
+		}
+	}
+	if result.Model != "" {
+		updates["prompt_version"] = promptVersion
+		updates["model_id"] = result.Model
+		updates["raw_output"] = result.RawOutput
+	}
+	if len(updates) > 0 {
+		err = db.Model(&models.Documents{}).Where("id = ?", document.ID).Updates(updates).Error
+		if err != nil {
+			log.Printf("Error guardando el análisis del documento %s: %v", document.ID, err)
+		}
+	}
+
+	return result.Lines, nil
 }
 
-func (r requestService) updateProduct(ctx context.Context, productsFind []bedrock.ProductResponse, db *gorm.DB, typeIngress int, clientAccountId uuid.UUID, requestId uuid.UUID) {
+// updateProduct aplica el stock de cada línea y deja los eventos en el outbox; tx debe ser la transacción del llamador
+func (r requestService) updateProduct(ctx context.Context, productsFind []lineItem, tx *gorm.DB, typeIngress int, clientAccountId uuid.UUID, requestId uuid.UUID) ([]uuid.UUID, error) {
 
 	listMovement := make([]eventservice.ProductPerMovement, 0, len(productsFind))
+	createdProducts := make([]uuid.UUID, 0)
+
+	matcher, err := newSkuMatcher(tx, clientAccountId, requestId, r.settings.MatchThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("cargando catálogo de la cuenta %s: %w", clientAccountId, err)
+	}
 
 	for _, product := range productsFind {
 
@@ -400,13 +674,29 @@ func (r requestService) updateProduct(ctx context.Context, productsFind []bedroc
 		var productUpdate models.Product
 
 		var existSku = false
+		match := skuMatch{}
 
-		existSku = findSku(product, db, &requestSku, existSku, ctx)
+		if product.ProductID != uuid.Nil {
+			requestSku.ProductID = product.ProductID
+			existSku = true
+			match = skuMatch{Found: true, Score: 1}
+		} else {
+			// las líneas ambiguas crean un producto nuevo y quedan para que el revisor elija entre los candidatos
+			match = matcher.match(product.ProductResponse)
+			if match.Found {
+				requestSku.ID = match.Candidate.SkuID
+				requestSku.NameSku = match.Candidate.Sku
+				requestSku.ProductID = match.Candidate.ProductID
+				existSku = true
+			}
+		}
 
 		if existSku {
 			countUpdate := product.Count * typeIngress
 
-			_ = db.First(&productUpdate, "id = ?", &requestSku.ProductID)
+			if err := tx.First(&productUpdate, "id = ?", &requestSku.ProductID).Error; err != nil {
+				return nil, fmt.Errorf("obteniendo producto del sku %s: %w", requestSku.NameSku, err)
+			}
 
 			productUpdate.Stock = productUpdate.Stock + countUpdate
 
@@ -414,16 +704,21 @@ func (r requestService) updateProduct(ctx context.Context, productsFind []bedroc
 
 				var message = "Producto con peligro de stock " + productUpdate.Name + " Stock actual: " + fmt.Sprintf("%d", productUpdate.Stock)
 				var now = time.Now()
-				r.db.Exec(
+				err := tx.Exec(
 					"INSERT INTO public.notification (message, type, is_read, date) VALUES (?, ?, ?, ?)",
 					message,
 					"product",
 					false,
 					now,
-				)
+				).Error
+				if err != nil {
+					return nil, err
+				}
 			}
 
-			db.Save(&productUpdate) //
+			if err := tx.Save(&productUpdate).Error; err != nil {
+				return nil, err
+			}
 		} else {
 
 			productUpdate.ID = uuid.New()
@@ -433,23 +728,34 @@ func (r requestService) updateProduct(ctx context.Context, productsFind []bedroc
 			productUpdate.Status = "active"
 			productUpdate.ClientAccount = clientAccountId
 
-			db.Create(&productUpdate)
+			if err := tx.Create(&productUpdate).Error; err != nil {
+				return nil, err
+			}
+			createdProducts = append(createdProducts, productUpdate.ID)
 
-			db.Save(&productUpdate) //
 			requestSku.ID = uuid.New()
-			requestSku.NameSku = product.SKUs[0]
+			requestSku.NameSku = normalizeSKU(product.Name)
+			if len(product.SKUs) > 0 {
+				requestSku.NameSku = product.SKUs[0]
+			}
 			requestSku.Status = true
 			requestSku.ProductID = productUpdate.ID
 			requestSku.CreatedAt = time.Now()
 
-			db.Create(&requestSku)
-
-			db.Save(&requestSku) //
+			if err := createRequestSku(tx, clientAccountId, requestSku); err != nil {
+				return nil, err
+			}
 		}
 
 		movement := createMovement(productUpdate, product.Count, typeIngress)
 		listMovement = append(listMovement, movement)
-		r.publicProductEtl(productUpdate, requestSku, clientAccountId, movement, typeIngress, requestId)
+		score := scoreLine(product, match, r.settings.ReviewThreshold)
+		if err := saveRequestLine(tx, requestId, movement.MovementId, productUpdate.ID, product, score, match.Candidates); err != nil {
+			return nil, err
+		}
+		if err := r.publicProductEtl(tx, productUpdate, requestSku, clientAccountId, movement, typeIngress, requestId); err != nil {
+			return nil, err
+		}
 	}
 
 	movementsRequest := eventservice.MovementsEvent{
@@ -458,9 +764,30 @@ func (r requestService) updateProduct(ctx context.Context, productsFind []bedroc
 		RequestId:          requestId,
 	}
 
-	r.eventMovement(movementsRequest)
+	if err := r.eventSvc.EnqueueMovements(tx, movementsRequest); err != nil {
+		return nil, err
+	}
 	notificationMovement()
 
+	return createdProducts, nil
+}
+
+// createRequestSku crea el SKU del producto nuevo con el mismo bloqueo y la misma validación de unicidad que el
+// catálogo. Si el código ya es de otro producto de la cuenta (la línea era ambigua) o no sirve como SKU, el producto
+// queda sin SKU y lo resuelve el revisor.
+func createRequestSku(tx *gorm.DB, clientAccountId uuid.UUID, sku models.Sku) error {
+	if err := stock.LockClientCatalog(tx, clientAccountId); err != nil {
+		return err
+	}
+	err := stock.EnsureSkuAvailable(tx, clientAccountId, sku)
+	if errors.Is(err, stock.ErrDuplicateSku) || errors.Is(err, stock.ErrInvalidSku) {
+		log.Printf("Producto %s creado sin SKU: %v", sku.ProductID, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Create(&sku).Error
 }
 
 func createMovement(product models.Product, count int, typeMovement int) eventservice.ProductPerMovement {
@@ -475,21 +802,13 @@ func createMovement(product models.Product, count int, typeMovement int) eventse
 	}
 }
 
-func (r requestService) eventMovement(movements eventservice.MovementsEvent) {
-
-	err := r.eventSvc.PublishMovements(movements)
-	if err != nil {
-		log.Printf("Error al publicar el evento de movimientos: %v", err)
-	}
-
-}
-
-func (r requestService) publicProductEtl(product models.Product, sku models.Sku, id uuid.UUID, movement eventservice.ProductPerMovement, typeIngress int, requestId uuid.UUID) {
+func (r requestService) publicProductEtl(tx *gorm.DB, product models.Product, sku models.Sku, id uuid.UUID, movement eventservice.ProductPerMovement, typeIngress int, requestId uuid.UUID) error {
 
-	err := r.eventSvc.PublishProductEtl(eventservice.ProductEvent{
+	return r.eventSvc.EnqueueProductEtl(tx, eventservice.ProductEvent{
 		ProductoID:      product.ID.String(),
 		NombreProducto:  product.Name,
 		ClienteID:       id.String(),
+		MovimientoID:    movement.MovementId.String(),
 		Cantidad:        movement.Count,
 		Signo:           typeIngress,
 		Fecha:           movement.CreatedAt,
@@ -497,33 +816,12 @@ func (r requestService) publicProductEtl(product models.Product, sku models.Sku,
 		StatusSolicitud: "pending",
 		TipoMovimiento:  fmt.Sprintf("%d", movement.MovementTypeId),
 	})
-	if err != nil {
-		return
-	}
 }
 
 func notificationMovement() {
 	//TODO implement me
 }
 
-func findSku(product bedrock.ProductResponse, db *gorm.DB, requestSku *models.Sku, existSku bool, ctx context.Context) bool {
-	for _, sku := range product.SKUs {
-
-		skuNormalized := normalizeSKU(sku)
-
-		resultSku := db.Where("name_sku ILIKE ?", "%"+skuNormalized+"%").Find(&requestSku)
-		if resultSku.Error != nil {
-			log.Printf("Error al procesar con Bedrock: %v", resultSku.Error)
-		}
-		if resultSku.RowsAffected > 0 {
-			existSku = true
-			break
-		}
-
-	}
-	return existSku
-}
-
 func normalizeSKU(s string) string {
 	s = strings.ToUpper(s)
 	s = strings.ReplaceAll(s, " ", "")
@@ -0,0 +1,90 @@
+package request
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const stalledBatchSize = 50
+
+// RequeueStalled vuelve a encolar las solicitudes que quedaron en created con un job de Textract ya iniciado
+// y sin intento exitoso, cuyo mensaje se perdió (p. ej. la instancia cayó a mitad del procesamiento).
+// Se saltan las que tuvieron actividad o un evento encolado en los últimos attemptStaleAfter y las que
+// ya fallaron maxFailures veces: esas agotaron sus reintentos y se reprocesan a mano.
+func (r requestService) RequeueStalled(ctx context.Context, maxFailures int) (int, error) {
+	cutoff := time.Now().Add(-attemptStaleAfter)
+
+	var requestIds []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&models.Request{}).
+		Where("request.status = ?", models.RequestCreated).
+		Where("EXISTS (SELECT 1 FROM documents d WHERE d.request_id = request.id AND d.textract_id IS NOT NULL AND d.textract_id <> '')").
+		Where("NOT EXISTS (SELECT 1 FROM request_attempt a WHERE a.request_id = request.id AND (a.status = ? OR COALESCE(a.finished_at, a.started_at) > ?))",
+			models.AttemptSucceeded, cutoff).
+		Where("(SELECT COUNT(*) FROM request_attempt a WHERE a.request_id = request.id AND a.status = ?) < ?", models.AttemptFailed, maxFailures).
+		Where("NOT EXISTS (SELECT 1 FROM outbox_event o WHERE o.routing_key = ? AND o.payload->>'request_id' = request.id::text AND o.created_at > ?)",
+			eventservice.RequestTopic, cutoff).
+		Order("request.create_at").
+		Limit(stalledBatchSize).
+		Pluck("request.id", &requestIds).Error
+	if err != nil {
+		return 0, err
+	}
+
+	requeued := 0
+	for _, requestId := range requestIds {
+		ok, err := r.requeueStalled(ctx, requestId)
+		if err != nil {
+			return requeued, err
+		}
+		if ok {
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (r requestService) requeueStalled(ctx context.Context, requestId uuid.UUID) (bool, error) {
+	requeued := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var requests []models.Request
+		// SKIP LOCKED: otra instancia o un reprocesamiento manual ya la está tomando
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ? AND status = ?", requestId, models.RequestCreated).
+			Limit(1).
+			Find(&requests).Error
+		if err != nil || len(requests) == 0 {
+			return err
+		}
+		request := requests[0]
+
+		var attempts []models.RequestAttempt
+		if err := tx.Where("request_id = ?", request.ID).Order("attempt DESC").Limit(1).Find(&attempts).Error; err != nil {
+			return err
+		}
+
+		event := createdRequestProcess(request.ID, request.ClientAccountID, dto.GetTypeMovementForDeltaUpdate(request.MovementTypeId))
+		if len(attempts) > 0 {
+			// se retoma con el mismo modelo y prompt del intento interrumpido
+			event.Model = attempts[0].Model
+			event.PromptVersion = attempts[0].PromptVersion
+		}
+		if err := r.eventSvc.EnqueueRequest(tx, event); err != nil {
+			return err
+		}
+
+		requeued = true
+		log.Printf("Solicitud %s detenida en created, se vuelve a encolar", request.ID)
+		return nil
+	})
+
+	return requeued, err
+}
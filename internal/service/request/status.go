@@ -0,0 +1,78 @@
+package request
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"gorm.io/gorm"
+)
+
+// SystemActor identifica los cambios de estado hechos por los consumidores y no por un usuario
+const SystemActor = "system"
+
+// transition cambia el estado validando la tabla de transiciones y deja el registro en el historial.
+// La actualización es condicional al estado de origen, así una carrera con otro cambio también falla.
+func transition(tx *gorm.DB, requestId uuid.UUID, from, to models.RequestStatus, actor string, reason string) error {
+	if !from.CanTransitionTo(to) {
+		return &TransitionError{RequestID: requestId, From: from, To: to}
+	}
+
+	result := tx.Model(&models.Request{}).
+		Where("id = ? AND status = ?", requestId, from).
+		Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &TransitionError{RequestID: requestId, From: from, To: to}
+	}
+
+	return recordStatus(tx, requestId, from, to, actor, reason)
+}
+
+func recordStatus(tx *gorm.DB, requestId uuid.UUID, from, to models.RequestStatus, actor string, reason string) error {
+	return tx.Create(&models.RequestStatusHistory{
+		ID:         uuid.New(),
+		RequestID:  requestId,
+		FromStatus: from,
+		ToStatus:   to,
+		ChangedBy:  actor,
+		Reason:     reason,
+	}).Error
+}
+
+func (r requestService) History(ctx context.Context, clientAccountId uuid.UUID, requestId uuid.UUID) ([]dto.RequestStatusHistoryDto, error) {
+	var request models.Request
+
+	err := r.db.WithContext(ctx).First(&request, "id = ? AND client_account_id = ?", requestId, clientAccountId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRequestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var history []models.RequestStatusHistory
+	err = r.db.WithContext(ctx).
+		Where("request_id = ?", requestId).
+		Order("created_at").
+		Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.RequestStatusHistoryDto, 0, len(history))
+	for _, h := range history {
+		items = append(items, dto.RequestStatusHistoryDto{
+			From:      h.FromStatus,
+			To:        h.ToStatus,
+			ChangedBy: h.ChangedBy,
+			Reason:    h.Reason,
+			CreatedAt: h.CreatedAt,
+		})
+	}
+	return items, nil
+}
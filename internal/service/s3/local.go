@@ -0,0 +1,66 @@
+package s3
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/stock-ahora/api-stock/internal/dto"
+)
+
+// LocalBucket es el bucket que se registra en los documentos guardados en disco
+const LocalBucket = "local"
+
+// LocalStorage guarda los documentos bajo un directorio, con las mismas keys que en S3.
+// Es el almacenamiento del backend de extracción local, para procesar solicitudes sin AWS.
+type LocalStorage struct {
+	dir string
+}
+
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{dir: dir}
+}
+
+func (s *LocalStorage) GetBucket() string {
+	return LocalBucket
+}
+
+func (s *LocalStorage) DoHandleUpload(upload dto.UploadFile, path string) (string, error) {
+	key := buildObjectKey(sanitizeFilename(upload.FileName), path)
+	target := s.path(key)
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", fmt.Errorf("error al crear directorio de documentos: %w", err)
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return "", fmt.Errorf("error al guardar archivo en disco: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, upload.File); err != nil {
+		return "", fmt.Errorf("error al guardar archivo en disco: %w", err)
+	}
+	return key, nil
+}
+
+func (s *LocalStorage) GetDocument(key string) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener archivo de disco: %w", err)
+	}
+	return file, nil
+}
+
+func (s *LocalStorage) DeleteDocument(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error al eliminar archivo de disco: %w", err)
+	}
+	return nil
+}
+
+// path ubica la key dentro del directorio; Clean sobre la key absoluta evita que un ".." salga de él
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.dir, filepath.Clean("/"+key))
+}
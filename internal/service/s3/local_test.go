@@ -0,0 +1,60 @@
+package s3
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stock-ahora/api-stock/internal/dto"
+)
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(t.TempDir(), "factura.txt")
+	if err := os.WriteFile(source, []byte("Tornillo;10;TOR-3MM\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	storage := NewLocalStorage(dir)
+	key, err := storage.DoHandleUpload(dto.UploadFile{File: file, FileName: "factura.txt", FileType: "text/plain"}, "requests/")
+	if err != nil {
+		t.Fatalf("DoHandleUpload: %v", err)
+	}
+	if !strings.HasPrefix(key, "requests/factura-") || !strings.HasSuffix(key, ".txt") {
+		t.Errorf("key = %s", key)
+	}
+
+	body, err := storage.GetDocument(key)
+	if err != nil {
+		t.Fatalf("GetDocument: %v", err)
+	}
+	defer body.Close()
+	content, _ := io.ReadAll(body)
+	if string(content) != "Tornillo;10;TOR-3MM\n" {
+		t.Errorf("contenido = %q", content)
+	}
+
+	if err := storage.DeleteDocument(key); err != nil {
+		t.Fatalf("DeleteDocument: %v", err)
+	}
+	if _, err := storage.GetDocument(key); err == nil {
+		t.Error("el documento sigue disponible después de eliminarlo")
+	}
+	if err := storage.DeleteDocument(key); err != nil {
+		t.Errorf("eliminar un documento que ya no existe: %v", err)
+	}
+}
+
+func TestLocalStorageStaysInDir(t *testing.T) {
+	storage := NewLocalStorage("/data/documents")
+	if got := storage.path("../../etc/passwd"); got != "/data/documents/etc/passwd" {
+		t.Errorf("path = %s", got)
+	}
+}
@@ -20,6 +20,14 @@ type S3config struct {
 	config.UploadService
 }
 
+// Storage guarda y entrega los documentos de las solicitudes: S3Svc en el bucket, LocalStorage en disco
+type Storage interface {
+	GetBucket() string
+	DoHandleUpload(upload dto.UploadFile, path string) (string, error)
+	GetDocument(key string) (io.ReadCloser, error)
+	DeleteDocument(key string) error
+}
+
 // implementación concreta
 type S3Svc struct {
 	config S3config
@@ -36,21 +44,21 @@ func (s *S3Svc) GetBucket() string {
 	return s.config.UploadService.Bucket
 }
 
-func (s *S3Svc) DoHandleUpload(createRequest *dto.CreateRequestDto, path string) (string, error) {
+func (s *S3Svc) DoHandleUpload(upload dto.UploadFile, path string) (string, error) {
 
 	s3Var := s.config
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	filename := sanitizeFilename(createRequest.FileName)
+	filename := sanitizeFilename(upload.FileName)
 	key := buildObjectKey(filename, path)
 
 	_, err := s3Var.Uploader.Upload(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s3Var.Bucket),
 		Key:         aws.String(key),
-		Body:        createRequest.File,
-		ContentType: aws.String(createRequest.FileType),
+		Body:        upload.File,
+		ContentType: aws.String(upload.FileType),
 	})
 	if err != nil {
 		return "", fmt.Errorf("error al cargar archivo en S3: %w", err)
@@ -75,6 +83,22 @@ func (s *S3Svc) GetDocument(key string) (io.ReadCloser, error) {
 	return result.Body, nil
 }
 
+func (s *S3Svc) DeleteDocument(key string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.config.S3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("error al eliminar archivo de S3: %w", err)
+	}
+
+	return nil
+}
+
 func buildObjectKey(filename, prefix string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
 	base := strings.TrimSuffix(filename, ext)
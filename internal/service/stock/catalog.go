@@ -0,0 +1,358 @@
+package stock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/eventservice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrProductNotFound = errors.New("producto no encontrado")
+	ErrSkuNotFound     = errors.New("SKU no encontrado en el producto")
+	ErrInvalidProduct  = errors.New("producto inválido")
+	ErrInvalidSku      = errors.New("SKU inválido")
+	ErrDuplicateSku    = errors.New("el SKU ya existe en otro producto de la cuenta")
+)
+
+func (s stockService) CreateProduct(ctx context.Context, clientAccountId uuid.UUID, productDto dto.CreateProductDto) (dto.ProductDto, error) {
+	product := models.Product{
+		ID:            uuid.New(),
+		Name:          strings.TrimSpace(productDto.Name),
+		Description:   strings.TrimSpace(productDto.Description),
+		Status:        productDto.Status,
+		ClientAccount: clientAccountId,
+		CreatedAt:     time.Now(),
+	}
+	if productDto.Referencial != nil {
+		product.ReferencialID = *productDto.Referencial
+	}
+	if product.Status == "" {
+		product.Status = models.ProductStatusActive
+	}
+	if err := validateProduct(product); err != nil {
+		return dto.ProductDto{}, err
+	}
+	if product.Status == models.ProductStatusArchived {
+		return dto.ProductDto{}, fmt.Errorf("%w: un producto no se puede crear archivado", ErrInvalidProduct)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := LockClientCatalog(tx, clientAccountId); err != nil {
+			return err
+		}
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+
+		for _, name := range productDto.Skus {
+			sku := models.Sku{
+				ID:        uuid.New(),
+				NameSku:   strings.TrimSpace(name),
+				Status:    true,
+				ProductID: product.ID,
+				CreatedAt: time.Now(),
+			}
+			if err := EnsureSkuAvailable(tx, clientAccountId, sku); err != nil {
+				return err
+			}
+			if err := tx.Create(&sku).Error; err != nil {
+				return err
+			}
+			product.Sku = append(product.Sku, sku)
+		}
+
+		return s.publishCatalog(tx, product)
+	})
+	if err != nil {
+		return dto.ProductDto{}, err
+	}
+
+	return toProductDto(product), nil
+}
+
+func (s stockService) UpdateProduct(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID, changes dto.UpdateProductDto) (dto.ProductDto, error) {
+	var product models.Product
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if product, err = findClientProduct(tx, clientAccountId, productId); err != nil {
+			return err
+		}
+
+		if changes.Name != nil {
+			product.Name = strings.TrimSpace(*changes.Name)
+		}
+		if changes.Description != nil {
+			product.Description = strings.TrimSpace(*changes.Description)
+		}
+		if changes.Referencial != nil {
+			product.ReferencialID = *changes.Referencial
+		}
+		if changes.Status != nil {
+			product.Status = *changes.Status
+		}
+		if err := validateProduct(product); err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Product{}).Where("id = ?", product.ID).Updates(map[string]any{
+			"name":           product.Name,
+			"description":    product.Description,
+			"referencial_id": product.ReferencialID,
+			"status":         product.Status,
+			"update_at":      time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return s.publishCatalog(tx, product)
+	})
+	if err != nil {
+		return dto.ProductDto{}, err
+	}
+
+	return s.productWithSkus(ctx, product.ID)
+}
+
+// ArchiveProduct deja el producto fuera de las nuevas solicitudes sin borrar su historial de movimientos
+func (s stockService) ArchiveProduct(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID) (dto.ProductDto, error) {
+	status := models.ProductStatusArchived
+	return s.UpdateProduct(ctx, clientAccountId, productId, dto.UpdateProductDto{Status: &status})
+}
+
+func (s stockService) Skus(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID) ([]dto.SkuDto, error) {
+	if _, err := findClientProduct(s.db.WithContext(ctx), clientAccountId, productId); err != nil {
+		return nil, err
+	}
+
+	var skus []models.Sku
+	if err := s.db.WithContext(ctx).Where("product_id = ?", productId).Order("created_at").Find(&skus).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.SkuDto, 0, len(skus))
+	for _, sku := range skus {
+		items = append(items, toSkuDto(sku))
+	}
+	return items, nil
+}
+
+func (s stockService) CreateSku(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID, skuDto dto.SaveSkuDto) (dto.SkuDto, error) {
+	sku := models.Sku{
+		ID:        uuid.New(),
+		NameSku:   strings.TrimSpace(skuDto.NameSku),
+		Status:    true,
+		ProductID: productId,
+		CreatedAt: time.Now(),
+	}
+	if skuDto.Status != nil {
+		sku.Status = *skuDto.Status
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		product, err := findClientProduct(tx, clientAccountId, productId)
+		if err != nil {
+			return err
+		}
+		if err := LockClientCatalog(tx, clientAccountId); err != nil {
+			return err
+		}
+		if err := EnsureSkuAvailable(tx, clientAccountId, sku); err != nil {
+			return err
+		}
+		if err := tx.Create(&sku).Error; err != nil {
+			return err
+		}
+		return s.publishCatalog(tx, product)
+	})
+	if err != nil {
+		return dto.SkuDto{}, err
+	}
+
+	return toSkuDto(sku), nil
+}
+
+func (s stockService) UpdateSku(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID, skuId uuid.UUID, skuDto dto.SaveSkuDto) (dto.SkuDto, error) {
+	var sku models.Sku
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		product, err := findClientProduct(tx, clientAccountId, productId)
+		if err != nil {
+			return err
+		}
+		if sku, err = findProductSku(tx, productId, skuId); err != nil {
+			return err
+		}
+
+		if name := strings.TrimSpace(skuDto.NameSku); name != "" {
+			sku.NameSku = name
+		}
+		if skuDto.Status != nil {
+			sku.Status = *skuDto.Status
+		}
+
+		if err := LockClientCatalog(tx, clientAccountId); err != nil {
+			return err
+		}
+		if err := EnsureSkuAvailable(tx, clientAccountId, sku); err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Sku{}).Where("id = ?", sku.ID).Updates(map[string]any{
+			"name_sku":   sku.NameSku,
+			"status":     sku.Status,
+			"updated_at": time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return s.publishCatalog(tx, product)
+	})
+	if err != nil {
+		return dto.SkuDto{}, err
+	}
+
+	return toSkuDto(sku), nil
+}
+
+func (s stockService) DeleteSku(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID, skuId uuid.UUID) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		product, err := findClientProduct(tx, clientAccountId, productId)
+		if err != nil {
+			return err
+		}
+		if _, err := findProductSku(tx, productId, skuId); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Sku{}, "id = ?", skuId).Error; err != nil {
+			return err
+		}
+		return s.publishCatalog(tx, product)
+	})
+}
+
+// publishCatalog deja en el outbox el estado actual del producto para que el ETL lo refleje en dim_producto
+func (s stockService) publishCatalog(tx *gorm.DB, product models.Product) error {
+	return s.eventSvc.EnqueueProductCatalog(tx, eventservice.ProductCatalogEvent{
+		ProductoID:     product.ID.String(),
+		NombreProducto: product.Name,
+		ClienteID:      product.ClientAccount.String(),
+		Status:         product.Status,
+	})
+}
+
+func (s stockService) productWithSkus(ctx context.Context, productId uuid.UUID) (dto.ProductDto, error) {
+	var product models.Product
+	err := s.db.WithContext(ctx).Preload("Sku").First(&product, "id = ?", productId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.ProductDto{}, ErrProductNotFound
+	}
+	if err != nil {
+		return dto.ProductDto{}, err
+	}
+	return toProductDto(product), nil
+}
+
+func findClientProduct(tx *gorm.DB, clientAccountId uuid.UUID, productId uuid.UUID) (models.Product, error) {
+	var product models.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&product, "id = ? AND client_account_id = ?", productId, clientAccountId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Product{}, ErrProductNotFound
+	}
+	return product, err
+}
+
+func findProductSku(tx *gorm.DB, productId uuid.UUID, skuId uuid.UUID) (models.Sku, error) {
+	var sku models.Sku
+	err := tx.First(&sku, "id = ? AND product_id = ?", skuId, productId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Sku{}, ErrSkuNotFound
+	}
+	return sku, err
+}
+
+// LockClientCatalog serializa los cambios de SKUs de una cuenta para que la validación de unicidad no tenga carreras;
+// también la toman las solicitudes que crean productos con su SKU
+func LockClientCatalog(tx *gorm.DB, clientAccountId uuid.UUID) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "product-catalog:"+clientAccountId.String()).Error
+}
+
+// skuNormalized es la forma con que el matcher y las solicitudes manuales comparan SKUs: "ABC-1" y "abc 1" son el mismo
+const skuNormalized = "regexp_replace(upper(%s), '[^A-Z0-9]', '', 'g')"
+
+// EnsureSkuAvailable rechaza un name_sku sin letras ni números o que, normalizado, ya use otro SKU de cualquier
+// producto de la cuenta
+func EnsureSkuAvailable(tx *gorm.DB, clientAccountId uuid.UUID, sku models.Sku) error {
+	if strings.IndexFunc(strings.ToUpper(sku.NameSku), isSkuChar) < 0 {
+		return fmt.Errorf("%w: name_sku es obligatorio y debe tener letras o números", ErrInvalidSku)
+	}
+
+	var taken int64
+	err := tx.Model(&models.Sku{}).
+		Joins("JOIN product ON product.id = sku.product_id").
+		Where("product.client_account_id = ? AND sku.id <> ?", clientAccountId, sku.ID).
+		Where(fmt.Sprintf(skuNormalized, "sku.name_sku")+" = "+fmt.Sprintf(skuNormalized, "?"), sku.NameSku).
+		Count(&taken).Error
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateSku, sku.NameSku)
+	}
+	return nil
+}
+
+func isSkuChar(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func validateProduct(product models.Product) error {
+	if product.Name == "" {
+		return fmt.Errorf("%w: el nombre es obligatorio", ErrInvalidProduct)
+	}
+	switch product.Status {
+	case models.ProductStatusActive, models.ProductStatusInactive, models.ProductStatusArchived:
+		return nil
+	default:
+		return fmt.Errorf("%w: estado %q desconocido", ErrInvalidProduct, product.Status)
+	}
+}
+
+func toProductDto(product models.Product) dto.ProductDto {
+	productDto := dto.ProductDto{
+		ID:          product.ID,
+		Referencial: product.ReferencialID,
+		Name:        product.Name,
+		Description: product.Description,
+		Stock:       product.Stock,
+		Status:      product.Status,
+		CreatedAt:   product.CreatedAt,
+		UpdatedAt:   product.UpdatedAt,
+	}
+	for _, sku := range product.Sku {
+		productDto.Skus = append(productDto.Skus, toSkuDto(sku))
+	}
+	return productDto
+}
+
+func toSkuDto(sku models.Sku) dto.SkuDto {
+	return dto.SkuDto{
+		ID:        sku.ID,
+		NameSku:   sku.NameSku,
+		Status:    sku.Status,
+		ProductId: sku.ProductID,
+		CreatedAt: sku.CreatedAt,
+		UpdatedAt: sku.UpdatedAt,
+	}
+}
@@ -1,23 +1,36 @@
 package stock
 
 import (
+	"context"
+
 	"github.com/google/uuid"
 	"github.com/stock-ahora/api-stock/internal/dto"
 	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/eventservice"
 	"gorm.io/gorm"
 )
 
 type StockService interface {
 	List(clientAccountId uuid.UUID, page, size int) (dto.Page[dto.ProductDto], error)
 	Get(productId uuid.UUID) (dto.ProductDto, error)
+	LowStock(clientAccountId uuid.UUID, threshold, limit int) ([]dto.ProductDto, error)
+
+	CreateProduct(ctx context.Context, clientAccountId uuid.UUID, product dto.CreateProductDto) (dto.ProductDto, error)
+	UpdateProduct(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID, changes dto.UpdateProductDto) (dto.ProductDto, error)
+	ArchiveProduct(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID) (dto.ProductDto, error)
+	Skus(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID) ([]dto.SkuDto, error)
+	CreateSku(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID, sku dto.SaveSkuDto) (dto.SkuDto, error)
+	UpdateSku(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID, skuId uuid.UUID, sku dto.SaveSkuDto) (dto.SkuDto, error)
+	DeleteSku(ctx context.Context, clientAccountId uuid.UUID, productId uuid.UUID, skuId uuid.UUID) error
 }
 
 type stockService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	eventSvc *eventservice.MQPublisher
 }
 
-func NewStockService(db *gorm.DB) StockService {
-	return &stockService{db: db}
+func NewStockService(db *gorm.DB, eventSvc *eventservice.MQPublisher) StockService {
+	return &stockService{db: db, eventSvc: eventSvc}
 }
 
 func (s stockService) List(clientAccountId uuid.UUID, page, size int) (dto.Page[dto.ProductDto], error) {
@@ -67,22 +80,31 @@ func (s stockService) Get(productId uuid.UUID) (dto.ProductDto, error) {
 	var product models.Product
 
 	err := s.db.
+		Preload("Sku").
 		Where("id = ?", productId).
 		Find(&product).Error
 	if err != nil {
 		return dto.ProductDto{}, err
 	}
 
-	productDto := dto.ProductDto{
-		ID:          product.ID,
-		Referencial: product.ReferencialID,
-		Name:        product.Name,
-		Description: product.Description,
-		Stock:       product.Stock,
-		Status:      product.Status,
-		CreatedAt:   product.CreatedAt,
-		UpdatedAt:   product.UpdatedAt,
+	return toProductDto(product), nil
+}
+
+// LowStock lista los productos del cliente con stock menor o igual a threshold, de menor a mayor stock
+func (s stockService) LowStock(clientAccountId uuid.UUID, threshold, limit int) ([]dto.ProductDto, error) {
+	var products []models.Product
+	if err := s.db.
+		Preload("Sku").
+		Where("client_account_id = ? AND stock <= ?", clientAccountId, threshold).
+		Order("stock ASC, name").
+		Limit(limit).
+		Find(&products).Error; err != nil {
+		return nil, err
 	}
 
-	return productDto, nil
+	items := make([]dto.ProductDto, 0, len(products))
+	for _, product := range products {
+		items = append(items, toProductDto(product))
+	}
+	return items, nil
 }
@@ -0,0 +1,75 @@
+package textract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// ErrJobNotFound indica que Textract ya no conoce el job (id inválido o resultados expirados); hay que iniciarlo de nuevo
+var ErrJobNotFound = errors.New("job de Textract inexistente o expirado")
+
+// StartError indica que Textract no aceptó el documento
+type StartError struct {
+	Bucket string
+	Key    string
+	Err    error
+}
+
+func (e *StartError) Error() string {
+	return fmt.Sprintf("no se pudo iniciar el análisis de s3://%s/%s: %v", e.Bucket, e.Key, e.Err)
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+// JobFailedError indica que el job terminó con error en Textract
+type JobFailedError struct {
+	JobID   string
+	Status  string
+	Message string
+}
+
+func (e *JobFailedError) Error() string {
+	return fmt.Sprintf("el análisis %s terminó en estado %s: %s", e.JobID, e.Status, e.Message)
+}
+
+// códigos de AWS que se resuelven reintentando más tarde
+var retryableCodes = map[string]bool{
+	"ThrottlingException":                    true,
+	"ProvisionedThroughputExceededException": true,
+	"LimitExceededException":                 true,
+	"InternalServerError":                    true,
+	"ServiceUnavailableException":            true,
+}
+
+// IsRetryable indica si el error es transitorio (contexto cancelado, límites de AWS, fallas de red) y conviene
+// reintentar en vez de rechazar la solicitud
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return retryableCodes[apiErr.ErrorCode()]
+	}
+
+	// la llamada no llegó a tener respuesta: conexión rechazada o cortada, DNS, timeouts de red
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &sendErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF)
+}
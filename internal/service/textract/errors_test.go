@@ -0,0 +1,42 @@
+package textract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"contexto vencido", fmt.Errorf("esperando job: %w", context.DeadlineExceeded), true},
+		{"throttling", &smithy.GenericAPIError{Code: "ThrottlingException"}, true},
+		{"parámetro inválido", &smithy.GenericAPIError{Code: "InvalidParameterException"}, false},
+		{"conexión cortada dentro de la operación", &smithy.OperationError{
+			ServiceID:     "Textract",
+			OperationName: "StartDocumentAnalysis",
+			Err:           &smithyhttp.RequestSendError{Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}},
+		}, true},
+		{"DNS", &net.DNSError{Err: "no such host", Name: "textract.us-east-1.amazonaws.com"}, true},
+		{"connection reset suelto", fmt.Errorf("leyendo respuesta: %w", syscall.ECONNRESET), true},
+		{"EOF inesperado", fmt.Errorf("leyendo respuesta: %w", io.ErrUnexpectedEOF), true},
+		{"job fallido", &JobFailedError{JobID: "job", Status: "FAILED", Message: "documento ilegible"}, false},
+		{"error cualquiera", errors.New("documento inválido"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable = %v; se esperaba %v", tt.name, got, tt.want)
+		}
+	}
+}
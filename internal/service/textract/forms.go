@@ -0,0 +1,321 @@
+package textract
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/textract/types"
+)
+
+// InvoiceHeader son los datos de cabecera de la factura leídos de los pares clave/valor (FORMS).
+// Los campos que no aparecen en el documento quedan vacíos o en nil.
+type InvoiceHeader struct {
+	SupplierName  string
+	SupplierTaxID string
+	Folio         string
+	IssueDate     *time.Time
+	Currency      string
+	Net           *float64
+	Tax           *float64
+	Total         *float64
+}
+
+func (h InvoiceHeader) IsEmpty() bool {
+	return h.SupplierName == "" && h.SupplierTaxID == "" && h.Folio == "" && h.IssueDate == nil &&
+		h.Currency == "" && h.Net == nil && h.Tax == nil && h.Total == nil
+}
+
+type CampoFormulario struct {
+	Clave string
+	Valor string
+}
+
+// extraerFormularios devuelve los pares clave/valor de los bloques KEY_VALUE_SET en orden de lectura
+func extraerFormularios(blocks []types.Block) []CampoFormulario {
+	blockMap := make(map[string]types.Block)
+	for _, block := range blocks {
+		if block.Id != nil {
+			blockMap[*block.Id] = block
+		}
+	}
+
+	var campos []CampoFormulario
+	for _, block := range blocks {
+		if block.BlockType != types.BlockTypeKeyValueSet || !esClave(block) {
+			continue
+		}
+
+		clave := textoHijos(block, blockMap)
+		valor := ""
+		for _, rel := range block.Relationships {
+			if rel.Type != types.RelationshipTypeValue {
+				continue
+			}
+			for _, id := range rel.Ids {
+				if texto := textoHijos(blockMap[id], blockMap); texto != "" {
+					if valor != "" {
+						valor += " "
+					}
+					valor += texto
+				}
+			}
+		}
+
+		if clave != "" && valor != "" {
+			campos = append(campos, CampoFormulario{Clave: clave, Valor: valor})
+		}
+	}
+	return campos
+}
+
+func esClave(block types.Block) bool {
+	for _, t := range block.EntityTypes {
+		if t == types.EntityTypeKey {
+			return true
+		}
+	}
+	return false
+}
+
+func textoHijos(block types.Block, blockMap map[string]types.Block) string {
+	var palabras []string
+	for _, rel := range block.Relationships {
+		if rel.Type != types.RelationshipTypeChild {
+			continue
+		}
+		for _, id := range rel.Ids {
+			child := blockMap[id]
+			if child.BlockType == types.BlockTypeWord && child.Text != nil {
+				palabras = append(palabras, *child.Text)
+			}
+		}
+	}
+	return strings.Join(palabras, " ")
+}
+
+// campoCabecera describe cómo reconocer un campo: claves en orden de preferencia y palabras que la descartan
+type campoCabecera struct {
+	claves    []string
+	excluidas []string
+}
+
+var (
+	datosCliente = []string{"cliente", "receptor", "comprador", "senor", "senores", "sr", "sres", "customer", "buyer", "bill"}
+	datosEmisor  = []string{"emisor", "proveedor", "vendedor", "supplier", "vendor", "seller"}
+
+	campoProveedor = campoCabecera{
+		claves:    []string{"razon social", "proveedor", "emisor", "vendedor", "supplier", "vendor", "seller"},
+		excluidas: datosCliente,
+	}
+	campoRutProveedor = campoCabecera{
+		claves:    []string{"rut", "r u t", "nit", "ruc", "rfc", "tax id", "vat number"},
+		excluidas: datosCliente,
+	}
+	campoFolio = campoCabecera{
+		claves: []string{"folio", "factura n", "factura no", "factura nro", "factura electronica n", "factura electronica no",
+			"factura electronica nro", "numero factura", "numero de factura", "invoice number", "invoice no", "nro"},
+		excluidas: []string{"orden", "guia", "pedido", "order", "cliente"},
+	}
+	campoFechaEmision = campoCabecera{
+		claves:    []string{"fecha emision", "fecha de emision", "issue date", "invoice date", "fecha", "date"},
+		excluidas: []string{"vencimiento", "venc", "due", "entrega", "pago"},
+	}
+	campoMoneda = campoCabecera{claves: []string{"moneda", "divisa", "currency"}}
+	campoNeto   = campoCabecera{claves: []string{"monto neto", "neto", "subtotal", "net amount", "net"}}
+	campoIVA    = campoCabecera{
+		claves:    []string{"iva", "impuesto", "impuestos", "tax", "vat"},
+		excluidas: []string{"id", "number", "exento"},
+	}
+	campoTotal = campoCabecera{
+		claves:    []string{"monto total", "total a pagar", "total factura", "total", "amount due"},
+		excluidas: []string{"subtotal", "neto", "iva", "impuesto", "impuestos", "exento", "tax", "vat"},
+	}
+)
+
+// ParseInvoiceHeader arma la cabecera a partir de los pares clave/valor del documento
+func ParseInvoiceHeader(campos []CampoFormulario) InvoiceHeader {
+	header := InvoiceHeader{
+		SupplierName:  buscarCampo(campos, campoProveedor),
+		SupplierTaxID: buscarRutProveedor(campos),
+		Folio:         limpiarFolio(buscarCampo(campos, campoFolio)),
+		Currency:      strings.ToUpper(buscarCampo(campos, campoMoneda)),
+		IssueDate:     parseFecha(buscarCampo(campos, campoFechaEmision)),
+	}
+
+	total := buscarCampo(campos, campoTotal)
+	header.Net = parseMonto(buscarCampo(campos, campoNeto))
+	header.Tax = parseMonto(buscarCampo(campos, campoIVA))
+	header.Total = parseMonto(total)
+
+	if header.Currency == "" {
+		header.Currency = monedaDesdeMonto(total)
+	}
+	return header
+}
+
+// buscarCampo recorre las claves en orden de preferencia y devuelve el valor de la primera que calce
+func buscarCampo(campos []CampoFormulario, campo campoCabecera) string {
+	for _, clave := range campo.claves {
+		patron := strings.Fields(clave)
+		for _, c := range campos {
+			palabras := palabrasClave(c.Clave)
+			if contienePalabras(palabras, patron) && !tieneAlguna(palabras, campo.excluidas) {
+				return strings.TrimSpace(c.Valor)
+			}
+		}
+	}
+	return ""
+}
+
+// buscarRutProveedor prefiere el RUT rotulado como del emisor. Sin rótulo, la factura suele traer también el RUT
+// del cliente con la misma clave: si aparecen RUT distintos no se sabe cuál es el del proveedor y queda vacío.
+func buscarRutProveedor(campos []CampoFormulario) string {
+	var rut string
+	distintos := make(map[string]bool)
+	for _, c := range campos {
+		palabras := palabrasClave(c.Clave)
+		if !calzaClave(palabras, campoRutProveedor.claves) || tieneAlguna(palabras, campoRutProveedor.excluidas) {
+			continue
+		}
+		valor := strings.TrimSpace(c.Valor)
+		if tieneAlguna(palabras, datosEmisor) {
+			return valor
+		}
+		if normalizado := normalizarRut(valor); !distintos[normalizado] {
+			distintos[normalizado] = true
+			if rut == "" {
+				rut = valor
+			}
+		}
+	}
+	if len(distintos) != 1 {
+		return ""
+	}
+	return rut
+}
+
+func calzaClave(palabras []string, claves []string) bool {
+	for _, clave := range claves {
+		if contienePalabras(palabras, strings.Fields(clave)) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizarRut(s string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "", "-", "", " ", "").Replace(s))
+}
+
+var noAlfanumerico = regexp.MustCompile(`[^a-z0-9%]+`)
+
+func palabrasClave(s string) []string {
+	s = strings.ToLower(s)
+	s = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ñ", "n", "°", " ", "º", " ").Replace(s)
+	return strings.Fields(noAlfanumerico.ReplaceAllString(s, " "))
+}
+
+// contienePalabras indica si patron aparece como secuencia de palabras completas dentro de palabras
+func contienePalabras(palabras, patron []string) bool {
+	for i := 0; i+len(patron) <= len(palabras); i++ {
+		ok := true
+		for j := range patron {
+			if palabras[i+j] != patron[j] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func tieneAlguna(palabras, excluidas []string) bool {
+	for _, p := range palabras {
+		for _, e := range excluidas {
+			if p == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func limpiarFolio(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimLeft(s, "N°º#.: ")
+	return strings.TrimSpace(s)
+}
+
+var layoutsFecha = []string{
+	"02-01-2006", "02/01/2006", "02.01.2006", "2006-01-02", "2/1/2006", "2-1-2006", "02-01-06", "02/01/06",
+}
+
+func parseFecha(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range layoutsFecha {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+var montoPermitido = regexp.MustCompile(`[^0-9,.\-]`)
+
+// parseMonto interpreta montos con separador de miles y decimales en formato chileno ("$ 1.234.567", "1.234,50")
+// o anglosajón ("1,234.50")
+func parseMonto(s string) *float64 {
+	s = montoPermitido.ReplaceAllString(s, "")
+	if s == "" {
+		return nil
+	}
+
+	lastDot := strings.LastIndex(s, ".")
+	lastComma := strings.LastIndex(s, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case lastComma >= 0:
+		if strings.Count(s, ",") > 1 || len(s)-lastComma == 4 {
+			s = strings.ReplaceAll(s, ",", "")
+		} else {
+			s = strings.Replace(s, ",", ".", 1)
+		}
+	case lastDot >= 0:
+		if strings.Count(s, ".") > 1 || len(s)-lastDot == 4 {
+			s = strings.ReplaceAll(s, ".", "")
+		}
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func monedaDesdeMonto(s string) string {
+	upper := strings.ToUpper(s)
+	for _, code := range []string{"USD", "EUR", "CLP", "UF"} {
+		if strings.Contains(upper, code) {
+			return code
+		}
+	}
+	if strings.Contains(upper, "US$") {
+		return "USD"
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package textract
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMonto(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *float64
+	}{
+		{"$ 1.234.567", ptr(1234567)},
+		{"1.234,50", ptr(1234.5)},
+		{"1.000", ptr(1000)},
+		{"12,5", ptr(12.5)},
+		{"US$ 1,234.50", ptr(1234.5)},
+		{"1,234", ptr(1234)},
+		{"1,234,567", ptr(1234567)},
+		{"99.95", ptr(99.95)},
+		{"", nil},
+		{"sin monto", nil},
+	}
+
+	for _, tt := range tests {
+		got := parseMonto(tt.in)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseMonto(%q) = %v; se esperaba %v", tt.in, deref(got), deref(tt.want))
+		}
+	}
+}
+
+func TestParseFecha(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"05-03-2025", "2025-03-05"},
+		{"05/03/2025", "2025-03-05"},
+		{"05.03.2025", "2025-03-05"},
+		{"2025-03-05", "2025-03-05"},
+		{"5/3/2025", "2025-03-05"},
+		{"05/03/25", "2025-03-05"},
+		{"marzo 2025", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if fecha := parseFecha(tt.in); fecha != nil {
+			got = fecha.Format(time.DateOnly)
+		}
+		if got != tt.want {
+			t.Errorf("parseFecha(%q) = %q; se esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuscarRutProveedor(t *testing.T) {
+	tests := []struct {
+		name   string
+		campos []CampoFormulario
+		want   string
+	}{
+		{
+			name:   "un solo RUT",
+			campos: []CampoFormulario{{"R.U.T.:", "76.123.456-7"}},
+			want:   "76.123.456-7",
+		},
+		{
+			name:   "RUT del cliente rotulado",
+			campos: []CampoFormulario{{"RUT Cliente", "12.345.678-9"}, {"RUT", "76.123.456-7"}},
+			want:   "76.123.456-7",
+		},
+		{
+			name:   "RUT del emisor rotulado gana",
+			campos: []CampoFormulario{{"RUT", "12.345.678-9"}, {"RUT Emisor", "76.123.456-7"}},
+			want:   "76.123.456-7",
+		},
+		{
+			name:   "dos RUT sin rótulo",
+			campos: []CampoFormulario{{"RUT", "76.123.456-7"}, {"RUT", "12.345.678-9"}},
+			want:   "",
+		},
+		{
+			name:   "el mismo RUT repetido",
+			campos: []CampoFormulario{{"RUT", "76.123.456-7"}, {"R.U.T.", "76123456-7"}},
+			want:   "76.123.456-7",
+		},
+		{
+			name:   "sin RUT",
+			campos: []CampoFormulario{{"Folio", "123"}},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := buscarRutProveedor(tt.campos); got != tt.want {
+			t.Errorf("%s: buscarRutProveedor = %q; se esperaba %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvoiceHeader(t *testing.T) {
+	chilena := ParseInvoiceHeader([]CampoFormulario{
+		{"Razón Social", "Ferretería Los Andes SpA"},
+		{"R.U.T.", "76.123.456-7"},
+		{"Señor(es)", "Comercial Sur Ltda"},
+		{"RUT", "12.345.678-9"},
+		{"FACTURA ELECTRONICA N°", "N° 4521"},
+		{"No", "99"},
+		{"Fecha Emisión", "05-03-2025"},
+		{"Fecha Vencimiento", "05-04-2025"},
+		{"Monto Neto", "$ 100.000"},
+		{"Total IVA", "$ 19.000"},
+		{"Total", "$ 119.000"},
+	})
+
+	if chilena.SupplierName != "Ferretería Los Andes SpA" {
+		t.Errorf("SupplierName = %q", chilena.SupplierName)
+	}
+	// el RUT del cliente viene con la misma clave: no se puede saber cuál es del proveedor
+	if chilena.SupplierTaxID != "" {
+		t.Errorf("SupplierTaxID = %q; se esperaba vacío", chilena.SupplierTaxID)
+	}
+	if chilena.Folio != "4521" {
+		t.Errorf("Folio = %q; se esperaba 4521", chilena.Folio)
+	}
+	if chilena.IssueDate == nil || chilena.IssueDate.Format(time.DateOnly) != "2025-03-05" {
+		t.Errorf("IssueDate = %v", chilena.IssueDate)
+	}
+	if deref(chilena.Net) != 100000.0 || deref(chilena.Tax) != 19000.0 || deref(chilena.Total) != 119000.0 {
+		t.Errorf("Net, Tax, Total = %v, %v, %v", deref(chilena.Net), deref(chilena.Tax), deref(chilena.Total))
+	}
+
+	// "Total IVA" no es el total de la factura
+	soloIVA := ParseInvoiceHeader([]CampoFormulario{{"Total IVA", "19.000"}, {"Total Exento", "5.000"}})
+	if soloIVA.Total != nil || deref(soloIVA.Tax) != 19000.0 {
+		t.Errorf("Total, Tax = %v, %v; se esperaba nil, 19000", soloIVA.Total, deref(soloIVA.Tax))
+	}
+
+	usa := ParseInvoiceHeader([]CampoFormulario{
+		{"Vendor", "Acme Corp"},
+		{"Tax ID", "98-7654321"},
+		{"Bill To", "Comercial Sur Ltda"},
+		{"Invoice Number", "INV-0042"},
+		{"Invoice Date", "2025-03-05"},
+		{"Subtotal", "1,000.00"},
+		{"Tax", "190.00"},
+		{"Amount Due", "USD 1,190.00"},
+	})
+	if usa.SupplierName != "Acme Corp" || usa.SupplierTaxID != "98-7654321" || usa.Folio != "INV-0042" {
+		t.Errorf("SupplierName, SupplierTaxID, Folio = %q, %q, %q", usa.SupplierName, usa.SupplierTaxID, usa.Folio)
+	}
+	if deref(usa.Net) != 1000.0 || deref(usa.Tax) != 190.0 || deref(usa.Total) != 1190.0 || usa.Currency != "USD" {
+		t.Errorf("Net, Tax, Total, Currency = %v, %v, %v, %q", deref(usa.Net), deref(usa.Tax), deref(usa.Total), usa.Currency)
+	}
+}
+
+func ptr(f float64) *float64 { return &f }
+
+func deref(f *float64) any {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
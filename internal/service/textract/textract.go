@@ -2,6 +2,7 @@ package textract
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -12,10 +13,27 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/textract/types"
 )
 
+// Service inicia y consulta análisis asíncronos de Textract. El JobId que devuelve StartAnalysis
+// permite retomar la espera con WaitAnalysis si el proceso se reinicia.
 type Service interface {
-	AnalyzeInvoice(ctx context.Context, bucket, key string) (*textract.AnalyzeDocumentOutput, error)
+	StartAnalysis(ctx context.Context, bucket, key string) (string, error)
+	WaitAnalysis(ctx context.Context, jobId string) (*Analysis, error)
 }
 
+// Analysis es el resultado de un análisis: tablas de productos y cabecera de la factura
+type Analysis struct {
+	JobID  string
+	Tablas []TablaProducto
+	Header InvoiceHeader
+	// Pages son las páginas analizadas, que es lo que cobra Textract
+	Pages int
+}
+
+const (
+	pollInicial = 2 * time.Second
+	pollMaximo  = 30 * time.Second
+)
+
 type TextractService struct {
 	client *textract.Client
 }
@@ -30,7 +48,7 @@ func NewTextractService(region string) *TextractService {
 	return &TextractService{client: client}
 }
 
-func (s *TextractService) AnalyzeInvoice(ctx context.Context, bucket, key string) (*[]TablaProducto, *string, error) {
+func (s *TextractService) StartAnalysis(ctx context.Context, bucket, key string) (string, error) {
 	input := &textract.StartDocumentAnalysisInput{
 		DocumentLocation: &types.DocumentLocation{
 			S3Object: &types.S3Object{
@@ -44,70 +62,92 @@ func (s *TextractService) AnalyzeInvoice(ctx context.Context, bucket, key string
 		},
 	}
 
-	resp, err := s.client.StartDocumentAnalysis(context.TODO(), input)
+	resp, err := s.client.StartDocumentAnalysis(ctx, input)
 	if err != nil {
-		log.Fatal(err)
+		return "", &StartError{Bucket: bucket, Key: key, Err: err}
 	}
-	jobId := resp.JobId
 
-	fmt.Println("Job ID:", *resp.JobId)
+	log.Printf("Textract job %s iniciado para %s", *resp.JobId, key)
+	return *resp.JobId, nil
+}
+
+// WaitAnalysis consulta el job con backoff hasta que termine o se cancele el contexto
+func (s *TextractService) WaitAnalysis(ctx context.Context, jobId string) (*Analysis, error) {
+	wait := pollInicial
 
-	var allBlocks []types.Block
 	for {
 		result, err := s.client.GetDocumentAnalysis(ctx, &textract.GetDocumentAnalysisInput{
-			JobId: aws.String(*jobId),
+			JobId: aws.String(jobId),
 		})
 		if err != nil {
-			return nil, nil, fmt.Errorf("error obteniendo resultado: %w", err)
+			var invalidJob *types.InvalidJobIdException
+			if errors.As(err, &invalidJob) {
+				return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobId)
+			}
+			return nil, fmt.Errorf("error obteniendo resultado del job %s: %w", jobId, err)
 		}
 
-		fmt.Println("Estado actual:", result.JobStatus)
-
-		if result.JobStatus == types.JobStatusSucceeded {
-			// Recolectar todos los bloques, incluyendo paginación
-			allBlocks = append(allBlocks, result.Blocks...)
+		log.Printf("Textract job %s estado: %s", jobId, result.JobStatus)
 
-			// Obtener bloques adicionales si hay paginación
-			for result.NextToken != nil {
-				nextResult, err := s.client.GetDocumentAnalysis(ctx, &textract.GetDocumentAnalysisInput{
-					JobId:     aws.String(*jobId),
-					NextToken: result.NextToken,
-				})
-				if err != nil {
-					return nil, nil, fmt.Errorf("error en paginación: %w", err)
-				}
-				allBlocks = append(allBlocks, nextResult.Blocks...)
-				result.NextToken = nextResult.NextToken
-			}
+		switch result.JobStatus {
+		case types.JobStatusSucceeded, types.JobStatusPartialSuccess:
+			return s.collect(ctx, jobId, result)
+		case types.JobStatusFailed:
+			return nil, &JobFailedError{JobID: jobId, Status: string(result.JobStatus), Message: aws.ToString(result.StatusMessage)}
+		}
 
-			// Extraer datos de tablas
-			tablas := extraerTablas(allBlocks)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("esperando el job %s: %w", jobId, ctx.Err())
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > pollMaximo {
+			wait = pollMaximo
+		}
+	}
+}
 
-			// Imprimir información para debug
-			for i, tabla := range tablas {
-				fmt.Printf("Tabla %d encontrada con %d filas\n", i+1, len(tabla.Filas))
-			}
+// collect junta las páginas de bloques del job terminado y arma tablas y cabecera
+func (s *TextractService) collect(ctx context.Context, jobId string, result *textract.GetDocumentAnalysisOutput) (*Analysis, error) {
+	allBlocks := append([]types.Block{}, result.Blocks...)
 
-			// Devolver análisis con los bloques para procesamiento posterior
-			return &tablas, resp.JobId, nil
+	nextToken := result.NextToken
+	for nextToken != nil {
+		nextResult, err := s.client.GetDocumentAnalysis(ctx, &textract.GetDocumentAnalysisInput{
+			JobId:     aws.String(jobId),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error en paginación: %w", err)
 		}
+		allBlocks = append(allBlocks, nextResult.Blocks...)
+		nextToken = nextResult.NextToken
+	}
 
-		if result.JobStatus == types.JobStatusFailed {
-			return nil, nil, fmt.Errorf("el análisis falló")
-		}
+	// Extraer datos de tablas
+	tablas := extraerTablas(allBlocks)
+	for i, tabla := range tablas {
+		log.Printf("Tabla %d encontrada con %d filas", i+1, len(tabla.Filas))
+	}
+
+	header := ParseInvoiceHeader(extraerFormularios(allBlocks))
 
-		// Esperar antes de volver a consultar
-		time.Sleep(2 * time.Second)
+	pages := 0
+	if result.DocumentMetadata != nil {
+		pages = int(aws.ToInt32(result.DocumentMetadata.Pages))
 	}
 
+	return &Analysis{JobID: jobId, Tablas: tablas, Header: header, Pages: pages}, nil
 }
 
 type TablaProducto struct {
 	Filas []FilaProducto
 }
 
+// FilaProducto guarda el texto de cada celda y la confianza de Textract (0-100) en la misma posición
 type FilaProducto struct {
-	Celdas []string
+	Celdas     []string
+	Confianzas []float32
 }
 
 // extraerTablas encuentra todas las tablas en los bloques y extrae su contenido
@@ -129,6 +169,7 @@ func extraerTablas(blocks []types.Block) []TablaProducto {
 
 			// Mapeo de celdas por coordenadas (fila, columna)
 			cellsByPosition := make(map[int]map[int]string)
+			confidenceByPosition := make(map[int]map[int]float32)
 
 			// Para cada relación del bloque tabla, obtener las celdas
 			if len(block.Relationships) > 0 {
@@ -146,6 +187,7 @@ func extraerTablas(blocks []types.Block) []TablaProducto {
 						// Inicializar el mapa de columnas si no existe
 						if _, exists := cellsByPosition[int(rowIdx)]; !exists {
 							cellsByPosition[rowIdx] = make(map[int]string)
+							confidenceByPosition[rowIdx] = make(map[int]float32)
 						}
 
 						// Extraer el texto de la celda
@@ -163,6 +205,7 @@ func extraerTablas(blocks []types.Block) []TablaProducto {
 						}
 
 						cellsByPosition[rowIdx][colIdx] = cellText
+						confidenceByPosition[rowIdx][colIdx] = aws.ToFloat32(cellBlock.Confidence)
 					}
 				}
 			}
@@ -188,12 +231,14 @@ func extraerTablas(blocks []types.Block) []TablaProducto {
 
 					// Crear fila con celdas ordenadas
 					fila := FilaProducto{
-						Celdas: make([]string, maxCols),
+						Celdas:     make([]string, maxCols),
+						Confianzas: make([]float32, maxCols),
 					}
 
 					for colIdx := 1; colIdx <= maxCols; colIdx++ {
 						if text, exists := rowMap[colIdx]; exists {
 							fila.Celdas[colIdx-1] = text
+							fila.Confianzas[colIdx-1] = confidenceByPosition[rowIdx][colIdx]
 						}
 					}
 
@@ -0,0 +1,239 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stock-ahora/api-stock/internal/config"
+	"github.com/stock-ahora/api-stock/internal/dto"
+	"github.com/stock-ahora/api-stock/internal/models"
+	"github.com/stock-ahora/api-stock/internal/service/bedrock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// operaciones registradas, para distinguir en el reporte de dónde viene cada llamada
+const (
+	OperationAnalyzeDocument   = "analyze_document"
+	OperationProductExtraction = "product_extraction"
+	OperationChatbot           = "chatbot"
+	OperationChatSession       = "chat_session"
+	OperationChatbotCatalog    = "chatbot_catalog"
+)
+
+var ErrInvalidQuota = errors.New("cuota inválida")
+
+// QuotaExceededError indica que la cuenta cliente agotó una de sus cuotas del mes.
+type QuotaExceededError struct {
+	ClientAccountID uuid.UUID
+	Resource        string
+	Used            float64
+	Limit           float64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("la cuenta %s agotó su cuota mensual de %s (%v de %v); las nuevas solicitudes OCR se rechazan hasta el próximo mes",
+		e.ClientAccountID, e.Resource, e.Used, e.Limit)
+}
+
+// price es el costo en USD por cada 1.000 tokens de entrada y de salida
+type price struct {
+	input  float64
+	output float64
+}
+
+// precios on-demand publicados por AWS; se busca por fragmento del id del modelo
+var modelPrices = []struct {
+	match string
+	price price
+}{
+	{"nova-pro", price{input: 0.0008, output: 0.0032}},
+	{"nova-lite", price{input: 0.00006, output: 0.00024}},
+	{"nova-micro", price{input: 0.000035, output: 0.00014}},
+	{"claude-3-haiku", price{input: 0.00025, output: 0.00125}},
+	{"claude-3-5-haiku", price{input: 0.0008, output: 0.004}},
+	{"sonnet", price{input: 0.003, output: 0.015}},
+}
+
+type Service interface {
+	// RecordTextract y RecordLLM guardan el consumo de una llamada; un error al guardar solo se registra en el log
+	RecordTextract(ctx context.Context, clientAccountId uuid.UUID, requestId *uuid.UUID, pages int)
+	RecordLLM(ctx context.Context, clientAccountId uuid.UUID, requestId *uuid.UUID, operation string, model string, usage bedrock.Usage)
+	// Report agrupa el consumo por día y por cliente; clientAccountId nil incluye todas las cuentas
+	Report(ctx context.Context, from, to time.Time, clientAccountId *uuid.UUID) (dto.UsageReportDto, error)
+	// CheckQuota devuelve *QuotaExceededError si la cuenta agotó alguna cuota del mes en curso
+	CheckQuota(ctx context.Context, clientAccountId uuid.UUID) error
+	SetQuota(ctx context.Context, quota dto.UsageQuotaDto) (dto.UsageQuotaDto, error)
+}
+
+type service struct {
+	db       *gorm.DB
+	settings config.UsageConfig
+}
+
+func NewService(db *gorm.DB, settings config.UsageConfig) Service {
+	return &service{db: db, settings: settings}
+}
+
+func (s *service) RecordTextract(ctx context.Context, clientAccountId uuid.UUID, requestId *uuid.UUID, pages int) {
+	if pages <= 0 {
+		return
+	}
+	s.record(ctx, models.UsageRecord{
+		ClientAccountID: clientAccountId,
+		RequestID:       requestId,
+		Kind:            models.UsageKindTextract,
+		Operation:       OperationAnalyzeDocument,
+		Pages:           pages,
+		EstimatedCost:   float64(pages) * s.settings.TextractPricePerPage,
+	})
+}
+
+func (s *service) RecordLLM(ctx context.Context, clientAccountId uuid.UUID, requestId *uuid.UUID, operation string, model string, usage bedrock.Usage) {
+	if usage.InputTokens == 0 && usage.OutputTokens == 0 {
+		return
+	}
+	p := s.priceFor(model)
+	s.record(ctx, models.UsageRecord{
+		ClientAccountID: clientAccountId,
+		RequestID:       requestId,
+		Kind:            models.UsageKindLLM,
+		Operation:       operation,
+		Model:           model,
+		InputTokens:     usage.InputTokens,
+		OutputTokens:    usage.OutputTokens,
+		EstimatedCost:   float64(usage.InputTokens)/1000*p.input + float64(usage.OutputTokens)/1000*p.output,
+	})
+}
+
+func (s *service) record(ctx context.Context, record models.UsageRecord) {
+	record.ID = uuid.New()
+	// el consumo se registra aunque el contexto de la llamada se haya cancelado: AWS ya la cobró
+	if err := s.db.WithContext(context.WithoutCancel(ctx)).Create(&record).Error; err != nil {
+		log.Printf("Error registrando consumo %s/%s de la cuenta %s: %v", record.Kind, record.Operation, record.ClientAccountID, err)
+	}
+}
+
+// priceFor usa los precios de configuración si están definidos y si no la tabla de modelos conocidos;
+// un modelo desconocido (p. ej. uno local) no tiene costo
+func (s *service) priceFor(model string) price {
+	if s.settings.LLMInputPer1K > 0 || s.settings.LLMOutputPer1K > 0 {
+		return price{input: s.settings.LLMInputPer1K, output: s.settings.LLMOutputPer1K}
+	}
+	model = strings.ToLower(model)
+	for _, known := range modelPrices {
+		if strings.Contains(model, known.match) {
+			return known.price
+		}
+	}
+	return price{}
+}
+
+// totalsSelect es la suma de consumo de las filas de usage_record agrupadas
+const totalsSelect = `COALESCE(SUM(pages), 0) AS textract_pages,
+	COUNT(*) FILTER (WHERE kind = 'llm') AS llm_calls,
+	COALESCE(SUM(input_tokens), 0) AS input_tokens,
+	COALESCE(SUM(output_tokens), 0) AS output_tokens,
+	COALESCE(SUM(estimated_cost), 0) AS estimated_cost`
+
+func (s *service) Report(ctx context.Context, from, to time.Time, clientAccountId *uuid.UUID) (dto.UsageReportDto, error) {
+	report := dto.UsageReportDto{From: from, To: to, ByDay: []dto.UsageByDayDto{}, ByClient: []dto.UsageByClientDto{}}
+
+	scope := func() *gorm.DB {
+		query := s.db.WithContext(ctx).Model(&models.UsageRecord{}).
+			Where("created_at >= ? AND created_at < ?", from, to)
+		if clientAccountId != nil {
+			query = query.Where("client_account_id = ?", *clientAccountId)
+		}
+		return query
+	}
+
+	if err := scope().Select(totalsSelect).Scan(&report.Total).Error; err != nil {
+		return report, err
+	}
+	err := scope().
+		Select("to_char(date_trunc('day', created_at), 'YYYY-MM-DD') AS day, " + totalsSelect).
+		Group("day").
+		Order("day").
+		Scan(&report.ByDay).Error
+	if err != nil {
+		return report, err
+	}
+	err = scope().
+		Select("client_account_id, " + totalsSelect).
+		Group("client_account_id").
+		Order("estimated_cost DESC").
+		Scan(&report.ByClient).Error
+	return report, err
+}
+
+func (s *service) CheckQuota(ctx context.Context, clientAccountId uuid.UUID) error {
+	quota, err := s.quota(ctx, clientAccountId)
+	if err != nil {
+		return err
+	}
+	if quota.MonthlyPages <= 0 && quota.MonthlyTokens <= 0 && quota.MonthlyCost <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var used dto.UsageTotalsDto
+	err = s.db.WithContext(ctx).Model(&models.UsageRecord{}).
+		Select(totalsSelect).
+		Where("client_account_id = ? AND created_at >= ?", clientAccountId, monthStart).
+		Scan(&used).Error
+	if err != nil {
+		return err
+	}
+
+	tokens := used.InputTokens + used.OutputTokens
+	switch {
+	case quota.MonthlyPages > 0 && used.TextractPages >= quota.MonthlyPages:
+		return &QuotaExceededError{ClientAccountID: clientAccountId, Resource: "páginas de Textract", Used: float64(used.TextractPages), Limit: float64(quota.MonthlyPages)}
+	case quota.MonthlyTokens > 0 && tokens >= quota.MonthlyTokens:
+		return &QuotaExceededError{ClientAccountID: clientAccountId, Resource: "tokens del LLM", Used: float64(tokens), Limit: float64(quota.MonthlyTokens)}
+	case quota.MonthlyCost > 0 && used.EstimatedCost >= quota.MonthlyCost:
+		return &QuotaExceededError{ClientAccountID: clientAccountId, Resource: "costo estimado (USD)", Used: used.EstimatedCost, Limit: quota.MonthlyCost}
+	}
+	return nil
+}
+
+// quota devuelve la cuota guardada de la cuenta o, si no tiene, la configurada por defecto
+func (s *service) quota(ctx context.Context, clientAccountId uuid.UUID) (models.UsageQuota, error) {
+	var quota models.UsageQuota
+	err := s.db.WithContext(ctx).First(&quota, "client_account_id = ?", clientAccountId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.UsageQuota{
+			ClientAccountID: clientAccountId,
+			MonthlyPages:    s.settings.MonthlyPages,
+			MonthlyTokens:   s.settings.MonthlyTokens,
+			MonthlyCost:     s.settings.MonthlyCost,
+		}, nil
+	}
+	return quota, err
+}
+
+func (s *service) SetQuota(ctx context.Context, quota dto.UsageQuotaDto) (dto.UsageQuotaDto, error) {
+	if quota.MonthlyPages < 0 || quota.MonthlyTokens < 0 || quota.MonthlyCost < 0 {
+		return quota, fmt.Errorf("%w: los límites no pueden ser negativos", ErrInvalidQuota)
+	}
+
+	record := models.UsageQuota{
+		ClientAccountID: quota.ClientAccountId,
+		MonthlyPages:    quota.MonthlyPages,
+		MonthlyTokens:   quota.MonthlyTokens,
+		MonthlyCost:     quota.MonthlyCost,
+	}
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"monthly_pages", "monthly_tokens", "monthly_cost", "updated_at"}),
+	}).Create(&record).Error
+	return quota, err
+}
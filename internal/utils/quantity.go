@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseQuantity lee una cantidad entera positiva escrita a la chilena, con punto de miles y coma decimal
+// ("1.000", "1.234,00", "12,0"), o con punto decimal ("12.0", "1,234.00"). Devuelve false si no es un número
+// positivo, si tiene una fracción real ("2,5", "1.234,5") o si admite dos lecturas ("1,000": mil o uno).
+// Es el único lector de cantidades: la salida del LLM, las celdas de Textract y las planillas pasan por aquí.
+func ParseQuantity(s string) (int, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if s == "" {
+		return 0, false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) && r != ',' && r != '.' {
+			return 0, false
+		}
+	}
+
+	lastComma := strings.LastIndex(s, ",")
+	lastDot := strings.LastIndex(s, ".")
+
+	var integer, fraction string
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		// el separador que va último es el decimal y el otro el de miles
+		decimal, thousands := lastComma, "."
+		if lastDot > lastComma {
+			decimal, thousands = lastDot, ","
+		}
+		integer, fraction = s[:decimal], s[decimal+1:]
+		if !groupedThousands(integer, thousands) {
+			return 0, false
+		}
+	case lastComma >= 0:
+		if strings.Count(s, ",") > 1 {
+			// 1,000,000
+			if !groupedThousands(s, ",") {
+				return 0, false
+			}
+			integer = s
+			break
+		}
+		integer, fraction = s[:lastComma], s[lastComma+1:]
+		if len(fraction) == 3 {
+			// "1,000" es mil con coma de miles o uno con coma decimal
+			return 0, false
+		}
+	case lastDot >= 0:
+		if groupedThousands(s, ".") {
+			integer = s
+			break
+		}
+		if strings.Count(s, ".") > 1 {
+			return 0, false
+		}
+		integer, fraction = s[:lastDot], s[lastDot+1:]
+	default:
+		integer = s
+	}
+
+	if strings.Trim(fraction, "0") != "" {
+		return 0, false
+	}
+	integer = strings.NewReplacer(".", "", ",", "").Replace(integer)
+	n, err := strconv.Atoi(integer)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// groupedThousands indica si s son dígitos agrupados de a tres con sep ("1.234.567"); sin sep basta con que tenga dígitos
+func groupedThousands(s string, sep string) bool {
+	parts := strings.Split(s, sep)
+	if len(parts) == 1 {
+		return s != "" && !strings.ContainsAny(s, ".,")
+	}
+	if len(parts[0]) == 0 || len(parts[0]) > 3 || strings.ContainsAny(parts[0], ".,") {
+		return false
+	}
+	for _, part := range parts[1:] {
+		if len(part) != 3 || strings.ContainsAny(part, ".,") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package utils
+
+import "testing"
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"12", 12, true},
+		{" 12 ", 12, true},
+		{"1.000", 1000, true},
+		{"1.234", 1234, true},
+		{"1.234.567", 1234567, true},
+		{"1.234,00", 1234, true},
+		{"1.234,5", 0, false},
+		{"12,0", 12, true},
+		{"12,00", 12, true},
+		{"2,5", 0, false},
+		{"12.0", 12, true},
+		{"12.5", 0, false},
+		{"1,234.00", 1234, true},
+		{"1,000,000", 1000000, true},
+		{"1,000", 0, false},
+		{"1.23", 0, false},
+		{"12.34.5", 0, false},
+		{"0", 0, false},
+		{"0,0", 0, false},
+		{"-3", 0, false},
+		{"", 0, false},
+		{"abc", 0, false},
+		{"1 000", 1000, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseQuantity(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseQuantity(%q) = %d, %v; se esperaba %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
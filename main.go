@@ -8,6 +8,7 @@ import (
 
 	"github.com/stock-ahora/api-stock/internal/config"
 	"github.com/stock-ahora/api-stock/internal/http"
+	"github.com/stock-ahora/api-stock/internal/service/s3"
 	"gorm.io/gorm"
 )
 
@@ -23,13 +24,13 @@ func main() {
 
 	//ejecutamos migraciones
 	config.RunMigrations(cfg.ToDBConfig())
+	config.RunStarMigrations(cfg.ToDBConfig())
 
-	//configuramos S3
-	s3 := config.S3ConfigService(cfg.ToS3Config())
+	//configuramos el almacenamiento de documentos
+	storage := getStorage(cfg)
 
-	log.Printf("S3 Configured: Bucket %s ", s3.Bucket)
 	_ = db.Exec("SELECT 1")
-	r := httpserver.NewRouter(*s3, db, DBStarts, nil, nil, cfg.S3Region, "", cfg.ToMQConfig())
+	r := httpserver.NewRouter(storage, db, DBStarts, nil, nil, cfg.S3Region, "", cfg.ToMQConfig())
 
 	addr := ":8082"
 	srv := &http.Server{
@@ -58,6 +59,19 @@ func getDbConfig(cfg *config.SecretApp) (*gorm.DB, *gorm.DB) {
 	return db, DBStarts
 }
 
+// getStorage usa S3, salvo con el extractor local, que guarda los documentos en disco para trabajar sin AWS
+func getStorage(cfg *config.SecretApp) s3.Storage {
+	extractorCfg := config.LoadExtractorConfig(cfg.S3Region)
+	if extractorCfg.Backend == config.ExtractorBackendLocal {
+		log.Printf("Documentos en disco: %s", extractorCfg.DocumentsDir)
+		return s3.NewLocalStorage(extractorCfg.DocumentsDir)
+	}
+
+	upload := config.S3ConfigService(cfg.ToS3Config())
+	log.Printf("S3 Configured: Bucket %s ", upload.Bucket)
+	return s3.NewS3Svs(s3.S3config{UploadService: *upload})
+}
+
 func getSecrets(ctx context.Context) *config.SecretApp {
 	cfg, err := config.LoadSecretManager(ctx)
 	if err != nil {